@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// errorWorkbookPath is where the annotated copy of a
+// workbook goes, next to it, such as "survey - errors.xlsx"
+func errorWorkbookPath(path string) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s - errors%s", strings.TrimSuffix(path, ext), ext)
+}
+
+type annotatedCell struct {
+	sheet    string
+	cell     string
+	messages []string
+}
+
+// AnnotateWorkbook writes a copy of the workbook with every cell of the
+// report highlighted and a comment on its problems. Problems with a
+// whole row go on the first cell of the row.
+func AnnotateWorkbook(path, out string, report ErrorReport) error {
+	f, err := excelize.OpenFile(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	style, err := f.NewStyle(&excelize.Style{
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"#FFC7CE"}, Pattern: 1},
+	})
+	if err != nil {
+		return err
+	}
+
+	cells := []*annotatedCell{}
+	byCell := map[string]*annotatedCell{}
+
+	for _, e := range report {
+		cell := e.Cell
+		if cell == "" {
+			cell = fmt.Sprintf("A%d", e.Row)
+		}
+
+		key := e.Sheet + "!" + cell
+		c, ok := byCell[key]
+		if !ok {
+			c = &annotatedCell{sheet: e.Sheet, cell: cell}
+			byCell[key] = c
+			cells = append(cells, c)
+		}
+		c.messages = append(c.messages, e.Message)
+	}
+
+	for _, c := range cells {
+		err := f.SetCellStyle(c.sheet, c.cell, c.cell, style)
+		if err != nil {
+			return err
+		}
+
+		comment, err := json.Marshal(map[string]string{
+			"author": "upload-typeform",
+			"text":   strings.Join(c.messages, "\n\n"),
+		})
+		if err != nil {
+			return err
+		}
+
+		err = f.AddComment(c.sheet, c.cell, string(comment))
+		if err != nil {
+			return err
+		}
+	}
+
+	return f.SaveAs(out)
+}
+
+// writeErrorWorkbooks writes an annotated copy of every Excel file with
+// rows in the report. Other sources only get the printed report.
+func writeErrorWorkbooks(report ErrorReport) {
+	files := []string{}
+	byFile := map[string]ErrorReport{}
+
+	for _, e := range report {
+		if _, ok := byFile[e.path]; !ok {
+			files = append(files, e.path)
+		}
+		byFile[e.path] = append(byFile[e.path], e)
+	}
+
+	for _, file := range files {
+		if strings.ToLower(filepath.Ext(file)) != ".xlsx" {
+			continue
+		}
+
+		out := errorWorkbookPath(file)
+		err := AnnotateWorkbook(file, out, byFile[file])
+		if err != nil {
+			fmt.Printf("Could not write the problems of %s to a copy: %s\n", file, err)
+			continue
+		}
+		fmt.Printf("Wrote %s, with the cells to fix highlighted\n", out)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestErrorWorkbookPath(t *testing.T) {
+	assert.Equal(t, "path/to/survey - errors.xlsx", errorWorkbookPath("path/to/survey.xlsx"))
+}
+
+func TestAnnotateWorkbook_HighlightsAndCommentsCells(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "survey.xlsx")
+	writeWorkbook(t, path, []testSheet{
+		{"Baseline", [][]string{
+			{"ref", "type", "question", "options"},
+			{"fruit", "multiple_choice", "Fruit?"},
+			{"name", "shortish_text", "Name?"},
+			{"age", "number", "Age?"},
+			{"age", "number", "Age again?"},
+			{"city", "short_text", ""},
+		}},
+		{"Endline", [][]string{
+			{"ref", "type", "question"},
+			{"hi", "statement", "Hi {{field:nmae}}"},
+		}},
+		{"Messages", [][]string{{"variable", "message"}}},
+	})
+
+	_, report, err := NewSurveyFile("workey", path).InitialForms()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Endline")
+
+	cells := []string{}
+	for _, e := range report {
+		cells = append(cells, e.Sheet+"!"+e.Cell)
+	}
+	assert.Equal(t, []string{"Baseline!D2", "Baseline!B3", "Baseline!A5", "Baseline!C6", "Endline!C2"}, cells)
+
+	out := errorWorkbookPath(path)
+	assert.Nil(t, AnnotateWorkbook(path, out, report))
+
+	f, err := excelize.OpenFile(out)
+	assert.Nil(t, err)
+	defer f.Close()
+
+	comments := map[string]string{}
+	for sheet, cs := range f.GetComments() {
+		for _, c := range cs {
+			comments[sheet+"!"+c.Ref] = c.Text
+		}
+	}
+	assert.Equal(t, 5, len(comments))
+	assert.Contains(t, comments["Baseline!D2"], "without options")
+	assert.Contains(t, comments["Baseline!B3"], "Unknown question type shortish_text")
+	assert.Contains(t, comments["Baseline!A5"], "The ref age is already used in row 4")
+	assert.Contains(t, comments["Baseline!C6"], "has no question")
+	assert.Contains(t, comments["Endline!C2"], "recalls question nmae")
+
+	style, err := f.GetCellStyle("Baseline", "D2")
+	assert.Nil(t, err)
+	assert.NotEqual(t, 0, style)
+
+	style, err = f.GetCellStyle("Baseline", "A2")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, style)
+
+	// the values of the cells are kept
+	value, _ := f.GetCellValue("Baseline", "C3")
+	assert.Equal(t, "Name?", value)
+}
+
+func TestAnnotateWorkbook_PutsRowProblemsOnFirstCell(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "survey.xlsx")
+	writeWorkbook(t, path, []testSheet{{"Baseline", [][]string{{"ref", "type", "question"}, {"a", "b", "c"}}}})
+
+	out := filepath.Join(t.TempDir(), "out.xlsx")
+	report := ErrorReport{
+		{Sheet: "Baseline", Row: 2, Message: "first"},
+		{Sheet: "Baseline", Row: 2, Message: "second"},
+	}
+	assert.Nil(t, AnnotateWorkbook(path, out, report))
+
+	f, err := excelize.OpenFile(out)
+	assert.Nil(t, err)
+	defer f.Close()
+
+	comments := f.GetComments()["Baseline"]
+	assert.Equal(t, 1, len(comments))
+	assert.Equal(t, "A2", comments[0].Ref)
+	assert.Contains(t, comments[0].Text, "first\n\nsecond")
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type AttachmentProperties struct {
+	Description string `json:"description,omitempty"`
+}
+
+type Attachment struct {
+	Type       string                `json:"type,omitempty"`
+	Href       string                `json:"href,omitempty"`
+	Scale      float64               `json:"scale,omitempty"`
+	Properties *AttachmentProperties `json:"properties,omitempty"`
+}
+
+type Layout struct {
+	Type       string      `json:"type,omitempty"`
+	Placement  string      `json:"placement,omitempty"`
+	Attachment *Attachment `json:"attachment,omitempty"`
+}
+
+// imageAttachment makes an image attachment from an attachment cell
+func imageAttachment(href string) *Attachment {
+	href = strings.TrimSpace(href)
+	if href == "" {
+		return nil
+	}
+	return &Attachment{Type: "image", Href: href}
+}
+
+var videoHosts = []string{"youtube.com", "youtu.be", "vimeo.com"}
+
+func isVideo(href string) bool {
+	u, err := url.Parse(href)
+	if err != nil || !isUrl(href) {
+		return false
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	for _, h := range videoHosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+var layoutTypes = []string{"stack", "split", "wallpaper", "float"}
+var layoutPlacements = []string{"left", "right"}
+var attachmentScales = []string{"0.4", "0.6", "0.8", "1"}
+
+// ParseAttachment builds the attachment of a field from the attachment
+// cell (a local image, an image URL or a YouTube/Vimeo URL) and the
+// attachment properties cell, such as "layout=split; placement=left".
+// Anything but a stack layout also puts the attachment in the layout.
+func ParseAttachment(cell, propertiesCell string) (*Attachment, *Layout, error) {
+	href := strings.TrimSpace(cell)
+	pairs, err := parseKeyValues(propertiesCell)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if href == "" {
+		if len(pairs) > 0 {
+			return nil, nil, fmt.Errorf("Attachment properties without an attachment")
+		}
+		return nil, nil, nil
+	}
+
+	attachment := imageAttachment(href)
+	if isVideo(href) {
+		attachment.Type = "video"
+	}
+
+	layout := &Layout{Type: "stack"}
+
+	for _, kv := range pairs {
+		switch kv.Key {
+		case "layout":
+			if !allows(layoutTypes, kv.Value) {
+				return nil, nil, fmt.Errorf("The layout should be one of %s, got: %s", strings.Join(layoutTypes, ", "), kv.Value)
+			}
+			layout.Type = kv.Value
+		case "placement":
+			if !allows(layoutPlacements, kv.Value) {
+				return nil, nil, fmt.Errorf("The placement should be one of %s, got: %s", strings.Join(layoutPlacements, ", "), kv.Value)
+			}
+			layout.Placement = kv.Value
+		case "scale":
+			if !allows(attachmentScales, kv.Value) {
+				return nil, nil, fmt.Errorf("The scale should be one of %s, got: %s", strings.Join(attachmentScales, ", "), kv.Value)
+			}
+			attachment.Scale, _ = strconv.ParseFloat(kv.Value, 64)
+		case "description":
+			attachment.Properties = &AttachmentProperties{Description: kv.Value}
+		default:
+			return nil, nil, fmt.Errorf("Unknown attachment property: %s", kv.Key)
+		}
+	}
+
+	if attachment.Type == "video" && layout.Type != "stack" {
+		return nil, nil, fmt.Errorf("Videos can only be shown with the stack layout")
+	}
+
+	if layout.Placement != "" && layout.Type == "stack" {
+		return nil, nil, fmt.Errorf("The placement only applies to split, wallpaper and float layouts")
+	}
+
+	if layout.Type == "stack" {
+		return attachment, nil, nil
+	}
+
+	layout.Attachment = attachment
+	return attachment, layout, nil
+}
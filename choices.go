@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// refs of choices have letters, numbers, - and _
+var choiceRefPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func checkChoiceRef(ref string) error {
+	if !choiceRefPattern.MatchString(ref) {
+		return fmt.Errorf("Choice refs can only have letters, numbers, - and _, got: %s", ref)
+	}
+	return nil
+}
+
+// parseChoiceRefs reads a choice_refs cell, a ref per line for the
+// choices in order. Empty lines are choices without a ref.
+func parseChoiceRefs(cell string) ([]string, error) {
+	if strings.TrimSpace(cell) == "" {
+		return nil, nil
+	}
+
+	refs := strings.Split(cell, "\n")
+	for i, ref := range refs {
+		refs[i] = strings.TrimSpace(ref)
+		if refs[i] == "" {
+			continue
+		}
+		if err := checkChoiceRef(refs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return refs, nil
+}
+
+// setChoiceRefs gives the choices the refs of their choice_refs cell
+func setChoiceRefs(choices []*FieldChoice, refs []string) error {
+	if len(refs) == 0 {
+		return nil
+	}
+
+	// a cell can end with empty lines
+	for len(refs) > len(choices) && refs[len(refs)-1] == "" {
+		refs = refs[:len(refs)-1]
+	}
+
+	if len(refs) != len(choices) {
+		return fmt.Errorf("There are %d choices but %d choice refs, cannot tell which ref goes with which choice", len(choices), len(refs))
+	}
+
+	for i, c := range choices {
+		c.Ref = refs[i]
+	}
+	return uniqueChoiceRefs(choices)
+}
+
+func uniqueChoiceRefs(choices []*FieldChoice) error {
+	seen := map[string]bool{}
+	for _, c := range choices {
+		if c.Ref == "" {
+			continue
+		}
+		if seen[c.Ref] {
+			return fmt.Errorf("More than one choice has the ref %s", c.Ref)
+		}
+		seen[c.Ref] = true
+	}
+	return nil
+}
+
+// header names of the Choices sheet, mapped to their canonical name
+var choiceListColumnAliases = map[string]string{
+	"list":   "list",
+	"name":   "list",
+	"label":  "label",
+	"choice": "label",
+	"ref":    "ref",
+}
+
+var requiredChoiceListColumns = []string{"list", "label"}
+
+// ChoiceLists are the named lists of choices of the Choices sheet
+type ChoiceLists map[string][]*FieldChoice
+
+// listName returns the name of the list in an options cell
+// such as "list:agree5", if it names one.
+func listName(options string) (string, bool) {
+	options = strings.TrimSpace(options)
+	if !strings.HasPrefix(options, "list:") {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(options, "list:")), true
+}
+
+// ParseChoiceLists reads the Choices sheet, a list name, a label and
+// an optional ref per row. The choices of a list are in sheet order.
+// Rows with a problem are left out, and returned as a ReportError.
+func ParseChoiceLists(records [][]string) (ChoiceLists, error) {
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	cols, err := parseColumns(records[0], choiceListColumnAliases, requiredChoiceListColumns)
+	if err != nil {
+		return nil, ErrorReport{sheetError("Choices", nil, 1, "", err)}.asError()
+	}
+
+	lists := ChoiceLists{}
+	report := ErrorReport{}
+	bad := func(row int, column string, err error) {
+		report = append(report, sheetError("Choices", cols, row, column, err))
+	}
+
+	for i, row := range records[1:] {
+		name := strings.TrimSpace(cols.Get(row, "list"))
+		label := strings.TrimSpace(cols.Get(row, "label"))
+		ref := strings.TrimSpace(cols.Get(row, "ref"))
+
+		if name == "" && label == "" && ref == "" {
+			continue
+		}
+
+		if name == "" || label == "" {
+			bad(i+2, "", fmt.Errorf("a choice needs a list and a label"))
+			continue
+		}
+
+		if ref != "" {
+			if err := checkChoiceRef(ref); err != nil {
+				bad(i+2, "ref", err)
+				continue
+			}
+		}
+
+		duplicate := false
+		for _, c := range lists[name] {
+			duplicate = duplicate || (ref != "" && c.Ref == ref)
+		}
+		if duplicate {
+			bad(i+2, "ref", fmt.Errorf("list %s has more than one choice with ref %s", name, ref))
+			continue
+		}
+
+		lists[name] = append(lists[name], &FieldChoice{Label: label, Ref: ref})
+	}
+
+	return lists, report.asError()
+}
+
+// options returns the list as an options cell and, if its
+// choices have refs, a choice_refs cell
+func (l ChoiceLists) options(name string) (string, string, bool) {
+	choices, ok := l[name]
+	if !ok {
+		return "", "", false
+	}
+
+	lines := make([]string, len(choices))
+	refs := make([]string, len(choices))
+	for i, c := range choices {
+		lines[i], refs[i] = c.Label, c.Ref
+	}
+
+	if strings.TrimSpace(strings.Join(refs, "")) == "" {
+		return strings.Join(lines, "\n"), "", true
+	}
+	return strings.Join(lines, "\n"), strings.Join(refs, "\n"), true
+}
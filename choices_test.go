@@ -0,0 +1,110 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseChoiceLists(t *testing.T) {
+	lists, err := ParseChoiceLists([][]string{
+		{"list", "label", "ref"},
+		{"agree3", "Disagree", "disagree"},
+		{"agree3", "Neutral", ""},
+		{},
+		{"yesno", "A. yes"},
+		{"agree3", "Agree", "agree"},
+		{"yesno", "B. no"},
+	})
+	assert.Nil(t, err)
+
+	assert.Equal(t, 3, len(lists["agree3"]))
+	assert.Equal(t, "Agree", lists["agree3"][2].Label)
+	assert.Equal(t, "agree", lists["agree3"][2].Ref)
+	assert.Equal(t, "", lists["agree3"][1].Ref)
+	assert.Equal(t, 2, len(lists["yesno"]))
+}
+
+func TestParseChoiceLists_ErrorsOnBadRows(t *testing.T) {
+	rows := [][]string{
+		{"agree3", ""},
+		{"", "Agree"},
+		{"agree3", "Agree again", "agree"},
+		{"agree3", "Agree again", "agree again"},
+	}
+
+	for _, row := range rows {
+		_, err := ParseChoiceLists([][]string{
+			{"list", "label", "ref"},
+			{"agree3", "Agree", "agree"},
+			row,
+		})
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "Choices sheet, row 3")
+	}
+}
+
+func TestBuildForm_ExpandsChoiceLists(t *testing.T) {
+	lists, _ := ParseChoiceLists([][]string{
+		{"list", "label", "ref"},
+		{"agree3", "Disagree", "disagree"},
+		{"agree3", "Neutral", "neutral"},
+		{"agree3", "Agree", "agree"},
+		{"yesno", "A. yes"},
+		{"yesno", "B. no"},
+	})
+
+	records := [][]string{
+		{"likes", "multiple_choice", "Do you like it?", "list:agree3"},
+		{"eats", "multiple_choice", "foo", "list: yesno"},
+		{"missing", "multiple_choice", "foo", "list:agree5"},
+	}
+
+	form, _, err := BuildForm("foo", DefaultColumns, lists, records)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(form.Fields))
+
+	choices := form.Fields[0].Properties.Choices
+	assert.Equal(t, 3, len(choices))
+	assert.Equal(t, "Neutral", choices[1].Label)
+	assert.Equal(t, "neutral", choices[1].Ref)
+	assert.Equal(t, "Do you like it?", form.Fields[0].Title)
+
+	// labels of lists are added to the title like those of options
+	assert.Equal(t, "foo\n\nA. yes\nB. no", form.Fields[1].Title)
+	assert.Equal(t, "A", form.Fields[1].Properties.Choices[0].Label)
+}
+
+func TestBuildForm_ExpandsChoiceListsOfMatrices(t *testing.T) {
+	lists, _ := ParseChoiceLists([][]string{
+		{"list", "label"},
+		{"agree2", "Disagree"},
+		{"agree2", "Agree"},
+	})
+
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "options", "group"})
+	records := [][]string{
+		{"opinions", "matrix", "What do you think?", "list:agree2"},
+		{"cats", "multiple_choice", "Cats", "", "opinions"},
+	}
+
+	form, _, err := BuildForm("foo", cols, lists, records)
+	assert.Nil(t, err)
+	assert.Equal(t, "Agree", form.Fields[0].Properties.Fields[0].Properties.Choices[1].Label)
+}
+
+func TestBuildForm_GivesMatrixChoiceRefsToItsRows(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "options", "group", "choice_refs"})
+	records := [][]string{
+		{"opinions", "matrix", "What do you think?", "Disagree\nNeutral\nAgree", "", "disagree\n\nagree"},
+		{"cats", "multiple_choice", "Cats", "", "opinions"},
+	}
+
+	form, _, err := BuildForm("foo", cols, nil, records)
+	assert.Nil(t, err)
+
+	choices := form.Fields[0].Properties.Fields[0].Properties.Choices
+	assert.Equal(t, "disagree", choices[0].Ref)
+	assert.Equal(t, "", choices[1].Ref)
+	assert.Equal(t, "agree", choices[2].Ref)
+}
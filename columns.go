@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Columns maps canonical column names (ref, type, question...) to
+// their position in a survey sheet.
+type Columns map[string]int
+
+// header names as they appear in sheets, mapped to their canonical name
+var columnAliases = map[string]string{
+	"ref":                   "ref",
+	"variable":              "ref",
+	"type":                  "type",
+	"question_type":         "type",
+	"question":              "question",
+	"title":                 "question",
+	"options":               "options",
+	"answers":               "options",
+	"choice_refs":           "choice_refs",
+	"description":           "description",
+	"properties":            "properties",
+	"required":              "required",
+	"validations":           "validations",
+	"group":                 "group",
+	"button_text":           "button_text",
+	"attachment":            "attachment",
+	"attachment_properties": "attachment_properties",
+	"redirect_url":          "redirect_url",
+	"share_icons":           "share_icons",
+	"score":                 "score",
+}
+
+var requiredColumns = []string{"ref", "type", "question"}
+
+// free-form columns for the authors, never read
+var ignoredColumns = map[string]bool{
+	"note":     true,
+	"notes":    true,
+	"comment":  true,
+	"comments": true,
+}
+
+func normalizeHeader(h string) string {
+	h = strings.ToLower(strings.TrimSpace(h))
+	return strings.Join(strings.Fields(h), "_")
+}
+
+// ParseColumns resolves the header row of a survey sheet. Empty headers,
+// note columns and columns starting with "#" are ignored. Any other
+// unknown header, duplicated header or missing required header is an error.
+func ParseColumns(header []string) (Columns, error) {
+	return parseColumns(header, columnAliases, requiredColumns)
+}
+
+func parseColumns(header []string, aliases map[string]string, required []string) (Columns, error) {
+	cols := Columns{}
+
+	for i, h := range header {
+		name := normalizeHeader(h)
+
+		if name == "" || ignoredColumns[name] || strings.HasPrefix(name, "#") {
+			continue
+		}
+
+		canonical, ok := aliases[name]
+		if !ok {
+			return nil, fmt.Errorf("Unknown column header: %s", h)
+		}
+
+		if _, exists := cols[canonical]; exists {
+			return nil, fmt.Errorf("More than one %s column (header: %s)", canonical, h)
+		}
+
+		cols[canonical] = i
+	}
+
+	missing := []string{}
+	for _, name := range required {
+		if _, ok := cols[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("Missing required column(s): %s", strings.Join(missing, ", "))
+	}
+
+	return cols, nil
+}
+
+// Get returns the value of the named column in the row, or an empty
+// string if the sheet doesn't have the column or the row is short.
+func (c Columns) Get(row []string, name string) string {
+	i, ok := c[name]
+	if !ok {
+		return ""
+	}
+	return get(row, i)
+}
+
+// Set returns a copy of the row with the named column set to the value,
+// or the row as it is if the sheet doesn't have the column.
+func (c Columns) Set(row []string, name, value string) []string {
+	i, ok := c[name]
+	if !ok {
+		return row
+	}
+	res := make([]string, len(row))
+	copy(res, row)
+	for len(res) <= i {
+		res = append(res, "")
+	}
+	res[i] = value
+	return res
+}
+
+// with returns the columns with the named one after the last column
+// of the sheet, if the sheet doesn't have it.
+func (c Columns) with(name string) Columns {
+	if _, ok := c[name]; ok {
+		return c
+	}
+
+	res := Columns{name: 0}
+	for n, i := range c {
+		res[n] = i
+		if i >= res[name] {
+			res[name] = i + 1
+		}
+	}
+	return res
+}
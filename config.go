@@ -1,8 +1,10 @@
 package main
 
 import (
+	"errors"
 	"fmt"
-	"github.com/xuri/excelize/v2"
+	"google.golang.org/api/option"
+	"os"
 	"path/filepath"
 	"strings"
 )
@@ -10,54 +12,222 @@ import (
 // workspace --base file
 // workspace --base file --translation file
 
+// SurveyFile is an Excel file, a directory of CSV files or a Google
+// spreadsheet, see OpenWorkbook
 type SurveyFile struct {
 	Workspace string
 	BaseName  string
 	Path      string
+
+	// options of the Sheets API client, for spreadsheets
+	SheetsOptions []option.ClientOption
+
+	// the sheets to make forms from, instead of every sheet
+	// but the special ones, and the sheet of the messages
+	Tabs        []string
+	MessagesTab string
+
+	// translations take the logic of their base forms,
+	// so a copy of the Logic sheet is not read
+	Translation bool
 }
 
 func NewSurveyFile(workspace, path string) *SurveyFile {
 
 	// create base name of form from filename itself
 	base := filepath.Base(path)
-	ext := filepath.Ext(base)
-	name := strings.ReplaceAll(base, ext, "")
+	name := base
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		ext := filepath.Ext(base)
+		name = strings.ReplaceAll(base, ext, "")
+	}
 
-	return &SurveyFile{
-		workspace, name, path,
+	// spreadsheets are named after their title, see InitialForms
+	if _, ok := spreadsheetID(path); ok {
+		name = ""
 	}
+
+	return &SurveyFile{Workspace: workspace, BaseName: name, Path: path}
+}
+
+// sheets of a workbook that are not forms
+var specialSheets = map[string]bool{
+	"Messages":  true,
+	"Logic":     true,
+	"Variables": true,
+	"Settings":  true,
+	"Choices":   true,
 }
 
-func (c *SurveyFile) InitialForms() (map[string]*FormConf, error) {
+// InitialForms builds a form for each form sheet of the survey. Rows
+// with a problem are left out of the forms and listed in the report.
+// Forms that cannot be built at all also list their rows in the report,
+// which is returned with the error.
+func (c *SurveyFile) InitialForms() (map[string]*FormConf, ErrorReport, error) {
 
-	f, err := excelize.OpenFile(c.Path)
+	f, err := OpenWorkbook(c.Path, c.SheetsOptions)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	messageRecords, err := f.GetRows("Messages")
+	if s, ok := f.(*googleSheet); ok && c.BaseName == "" {
+		c.BaseName = s.Title()
+	}
+
+	messagesTab := c.MessagesTab
+	if messagesTab == "" {
+		messagesTab = "Messages"
+	}
+
+	messageRecords, err := f.Rows(messagesTab)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	sheets := f.GetSheetList()
+	sheets := f.Sheets()
 	forms := map[string]*FormConf{}
+	report := ErrorReport{}
+	failed := []string{}
+
+	formSheets, err := c.formSheets(sheets, messagesTab)
+	if err != nil {
+		return nil, nil, err
+	}
 
+	var logicRecords, variableRecords, settingRecords, choiceRecords [][]string
 	for _, s := range sheets {
-		if s != "Messages" {
-			finalName := fmt.Sprintf("%s - %s", c.BaseName, s)
-			formRecords, err := f.GetRows(s)
-			if err != nil {
-				return nil, err
+		switch s {
+		case "Logic":
+			if !c.Translation {
+				logicRecords, err = f.Rows(s)
+			}
+		case "Variables":
+			variableRecords, err = f.Rows(s)
+		case "Settings":
+			settingRecords, err = f.Rows(s)
+		case "Choices":
+			choiceRecords, err = f.Rows(s)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// rows of the special sheets with a problem are left out and reported
+	sheetRows := func(err error) error {
+		var re *ReportError
+		if errors.As(err, &re) {
+			report = append(report, re.Report.in(c.Path, "")...)
+			return nil
+		}
+		return err
+	}
+
+	variables, err := ParseVariables(variableRecords)
+	if err := sheetRows(err); err != nil {
+		return nil, nil, err
+	}
+
+	settings, err := ParseSettings(settingRecords)
+	if err := sheetRows(err); err != nil {
+		return nil, nil, err
+	}
+
+	lists, err := ParseChoiceLists(choiceRecords)
+	if err := sheetRows(err); err != nil {
+		return nil, nil, err
+	}
+
+	for _, s := range formSheets {
+		finalName := fmt.Sprintf("%s - %s", c.BaseName, s)
+		formRecords, err := f.Rows(s)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		conf, err := NewFormConf(c.Workspace, finalName, formRecords, messageRecords, lists)
+
+		// keep going, to report the rows of every sheet
+		var re *ReportError
+		if errors.As(err, &re) {
+			report = append(report, re.Report.in(c.Path, s)...)
+			failed = append(failed, s)
+			continue
+		}
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Could not build form from sheet %s: %w", s, err)
+		}
+		for _, e := range conf.Errors {
+			if e.Sheet == "Messages" {
+				e.Sheet = messagesTab
 			}
+		}
+		report = append(report, conf.Errors.in(c.Path, s)...)
+		conf.Dir = c.dir()
+		conf.Sheet = s
+		conf.LogicData = logicRecords
+		conf.Form.Variables = copyVariables(variables)
+		conf.Form.Settings, err = mergeSettings(settings, nil)
+		if err != nil {
+			return nil, nil, err
+		}
 
-			conf, err := NewFormConf(c.Workspace, finalName, formRecords, messageRecords)
-			if err != nil {
-				return nil, err
+		conf.Form.Logic, err = BuildLogic(conf.Form, s, logicRecords)
+		if err := sheetRows(err); err != nil {
+			return nil, nil, fmt.Errorf("Could not build logic for sheet %s: %w", s, err)
+		}
+		forms[s] = conf
+	}
+
+	report = report.unique()
+
+	if len(failed) > 0 {
+		return nil, report, fmt.Errorf("Could not build forms from sheets %s, see the report", strings.Join(failed, ", "))
+	}
+
+	return forms, report, nil
+}
+
+func copyVariables(variables map[string]float64) map[string]float64 {
+	if variables == nil {
+		return nil
+	}
+	res := map[string]float64{}
+	for k, v := range variables {
+		res[k] = v
+	}
+	return res
+}
+
+// dir is the directory that local images are relative to
+func (c *SurveyFile) dir() string {
+	if info, err := os.Stat(c.Path); err == nil && info.IsDir() {
+		return c.Path
+	}
+	return filepath.Dir(c.Path)
+}
+
+// formSheets lists the sheets to make forms from
+func (c *SurveyFile) formSheets(sheets []string, messagesTab string) ([]string, error) {
+	if len(c.Tabs) == 0 {
+		res := []string{}
+		for _, s := range sheets {
+			if !specialSheets[s] && s != messagesTab {
+				res = append(res, s)
 			}
-			forms[s] = conf
 		}
+		return res, nil
 	}
 
-	return forms, nil
+	for _, tab := range c.Tabs {
+		found := false
+		for _, s := range sheets {
+			found = found || s == tab
+		}
+		if !found {
+			return nil, fmt.Errorf("Could not find tab %s in %s", tab, c.Path)
+		}
+	}
+	return c.Tabs, nil
 }
@@ -2,12 +2,14 @@ package main
 
 import (
 	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+	"path/filepath"
 	"testing"
 )
 
 func TestInitialForms_HappyPath(t *testing.T) {
 	cf := NewSurveyFile("workey", "test/Survey Translation Example.xlsx")
-	forms, err := cf.InitialForms()
+	forms, _, err := cf.InitialForms()
 	assert.Nil(t, err)
 
 	assert.Equal(t, forms["Baseline"].Name, "Survey Translation Example - Baseline")
@@ -17,3 +19,100 @@ func TestInitialForms_HappyPath(t *testing.T) {
 
 	assert.Equal(t, "Hello! We would like to take your time!", forms["Baseline"].Form.Fields[0].Title)
 }
+
+type testSheet struct {
+	name string
+	rows [][]string
+}
+
+func writeWorkbook(t *testing.T, path string, sheets []testSheet) {
+	f := excelize.NewFile()
+	for i, s := range sheets {
+		if i == 0 {
+			f.SetSheetName("Sheet1", s.name)
+		} else {
+			f.NewSheet(s.name)
+		}
+
+		for r, row := range s.rows {
+			for c, value := range row {
+				cell, _ := excelize.CoordinatesToCellName(c+1, r+1)
+				f.SetCellValue(s.name, cell, value)
+			}
+		}
+	}
+	assert.Nil(t, f.SaveAs(path))
+}
+
+func TestInitialForms_BuildsLogicFromLogicSheet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "survey.xlsx")
+	writeWorkbook(t, path, []testSheet{
+		{"Baseline", [][]string{
+			{"ref", "type", "question", "options"},
+			{"consent", "multiple_choice", "Consent?", "Yes\nNo"},
+			{"name", "short_text", "Name?"},
+			{"optout", "thankyou_screen", "Bye"},
+		}},
+		{"Messages", [][]string{{"variable", "message"}}},
+		{"Logic", [][]string{
+			{"form", "field", "choice", "jump_to"},
+			{"Baseline", "consent", "No", "optout"},
+		}},
+	})
+
+	forms, _, err := NewSurveyFile("workey", path).InitialForms()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(forms))
+
+	conf := forms["Baseline"]
+	assert.Equal(t, "Baseline", conf.Sheet)
+	assert.Contains(t, string(conf.Form.Logic), `"to":{"type":"thankyou","value":"optout"}`)
+	assert.Equal(t, "consent_2", conf.Form.Fields[0].Properties.Choices[1].Ref)
+}
+
+func TestInitialForms_ReadsVariablesSheet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "survey.xlsx")
+	writeWorkbook(t, path, []testSheet{
+		{"Quiz", [][]string{
+			{"ref", "type", "question", "options", "score"},
+			{"capital", "multiple_choice", "Capital of France?", "Paris\nLyon", "Paris=1"},
+		}},
+		{"Messages", [][]string{{"variable", "message"}}},
+		{"Variables", [][]string{
+			{"variable", "value"},
+			{"price", "5"},
+		}},
+	})
+
+	forms, _, err := NewSurveyFile("workey", path).InitialForms()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(forms))
+
+	conf := forms["Quiz"]
+	assert.Equal(t, map[string]float64{"price": 5, "score": 0}, conf.Form.Variables)
+	assert.Contains(t, string(conf.Form.Logic), `"action":"add"`)
+}
+
+func TestInitialForms_ReadsSettingsSheet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "survey.xlsx")
+	writeWorkbook(t, path, []testSheet{
+		{"Baseline", [][]string{
+			{"ref", "type", "question"},
+			{"name", "short_text", "Name?"},
+		}},
+		{"Messages", [][]string{{"variable", "message"}}},
+		{"Settings", [][]string{
+			{"setting", "value"},
+			{"language", "es"},
+			{"is_public", "no"},
+		}},
+	})
+
+	forms, _, err := NewSurveyFile("workey", path).InitialForms()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(forms))
+
+	settings := forms["Baseline"].Form.Settings
+	assert.Equal(t, "es", settings.Language)
+	assert.Equal(t, false, *settings.IsPublic)
+}
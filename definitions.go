@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// readDefinition reads a JSON or YAML file into v. YAML is read through
+// JSON, so that v only needs json tags.
+func readDefinition(path string, v interface{}) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+	case ".yaml", ".yml":
+		var doc interface{}
+		err = yaml.Unmarshal(b, &doc)
+		if err != nil {
+			return fmt.Errorf("Could not read %s: %w", path, err)
+		}
+
+		b, err = json.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("Could not read %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("Could not read %s, expected a .json, .yaml or .yml file", path)
+	}
+
+	err = json.Unmarshal(b, v)
+	if err != nil {
+		return fmt.Errorf("Could not read %s: %w", path, err)
+	}
+	return nil
+}
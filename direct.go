@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FormDefinition is a form written as a JSON or YAML file, in the shape
+// of a Typeform form, with the messages of the form.
+type FormDefinition struct {
+	Form
+	Messages map[string]string `json:"messages,omitempty"`
+}
+
+func (d *FormDefinition) messagesData() [][]string {
+	keys := []string{}
+	for k := range d.Messages {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	records := [][]string{{"variable", "message"}}
+	for _, k := range keys {
+		records = append(records, []string{k, d.Messages[k]})
+	}
+	return records
+}
+
+// checkFieldDefinition checks what a sheet row would have checked
+// while building the field
+func checkFieldDefinition(f *Field, inGroup string) []string {
+	problems := []string{}
+	add := func(format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf("Question %s: %s", f.Ref, fmt.Sprintf(format, a...)))
+	}
+
+	if _, ok := fieldTypeProperties[f.Type]; !ok {
+		add("unknown type %s", f.Type)
+	}
+	if strings.TrimSpace(f.Title) == "" {
+		add("the title is empty")
+	}
+
+	if inGroup != "" && (f.Type == "group" || f.Type == "matrix") {
+		add("the %s cannot be put inside of another group (%s)", f.Type, inGroup)
+	}
+
+	if f.Validations != nil && f.Validations.Required {
+		if _, ok := fieldTypeValidations[f.Type]; !ok {
+			add("%s questions cannot be required", f.Type)
+		}
+	}
+
+	if choiceTypes[f.Type] || f.Type == "picture_choice" {
+		if f.Properties == nil || len(f.Properties.Choices) == 0 {
+			add("%s questions need choices", f.Type)
+		} else if err := uniqueChoiceRefs(f.Properties.Choices); err != nil {
+			add("%s", err)
+		}
+	}
+
+	if f.Properties != nil {
+		for _, g := range f.Properties.Fields {
+			if f.Type == "matrix" && g.Type != "multiple_choice" {
+				problems = append(problems, fmt.Sprintf("Question %s is a row of matrix %s, so it must be multiple_choice, not %s", g.Ref, f.Ref, g.Type))
+			}
+			problems = append(problems, checkFieldDefinition(g, f.Ref)...)
+		}
+	}
+
+	return problems
+}
+
+// checkLogicDefinition checks that the logic of the form is on its
+// questions and that jumps only go forward, like buildJump
+func checkLogicDefinition(form *Form) []string {
+	if len(form.Logic) == 0 {
+		return nil
+	}
+
+	logic := []*FieldLogic{}
+	if err := json.Unmarshal(form.Logic, &logic); err != nil {
+		return []string{fmt.Sprintf("Could not read the logic: %s", err)}
+	}
+
+	fields := flattenFields(form.Fields)
+	thankyou := map[string]bool{}
+	for _, s := range form.ThankYouScreens {
+		thankyou[s.Ref] = true
+	}
+
+	problems := []string{}
+	for _, l := range logic {
+		from := fieldIndex(l.Ref, fields)
+		if from == -1 {
+			problems = append(problems, fmt.Sprintf("Logic: could not find question %s", l.Ref))
+			continue
+		}
+
+		for _, a := range l.Actions {
+			if a.Condition != nil {
+				for _, v := range a.Condition.Vars {
+					if v.Type == "choice" && !hasChoiceRef(fields[from], fmt.Sprint(v.Value)) {
+						problems = append(problems, fmt.Sprintf("Logic: question %s has no choice with the ref %v", l.Ref, v.Value))
+					}
+				}
+			}
+
+			if a.Action != "jump" {
+				continue
+			}
+			if a.Details == nil || a.Details.To == nil {
+				problems = append(problems, fmt.Sprintf("Logic: a jump of question %s has nowhere to go", l.Ref))
+				continue
+			}
+
+			to := fmt.Sprint(a.Details.To.Value)
+			switch a.Details.To.Type {
+			case "thankyou":
+				if !thankyou[to] {
+					problems = append(problems, fmt.Sprintf("Logic: could not find thankyou screen %s to jump to", to))
+				}
+			case "field":
+				if i := fieldIndex(to, fields); i == -1 {
+					problems = append(problems, fmt.Sprintf("Logic: could not find question %s to jump to", to))
+				} else if i <= from {
+					problems = append(problems, fmt.Sprintf("Logic: question %s cannot jump back to %s", l.Ref, to))
+				}
+			default:
+				problems = append(problems, fmt.Sprintf("Logic: question %s jumps to a %s", l.Ref, a.Details.To.Type))
+			}
+		}
+	}
+	return problems
+}
+
+func hasChoiceRef(f *Field, ref string) bool {
+	if f.Properties == nil {
+		return false
+	}
+	for _, c := range f.Properties.Choices {
+		if c.Ref == ref {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateForm checks a form written by hand before it is sent to
+// Typeform, which would only answer with the first problem.
+func ValidateForm(form *Form) error {
+	problems := formProblems(form)
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+func formProblems(form *Form) []string {
+	problems := []string{}
+
+	if strings.TrimSpace(form.Title) == "" {
+		problems = append(problems, "The form has no title")
+	}
+
+	refs := map[string]bool{}
+	checkRef := func(kind, ref string) {
+		if ref == "" {
+			problems = append(problems, fmt.Sprintf("A %s has no ref", kind))
+		} else if refs[ref] {
+			problems = append(problems, fmt.Sprintf("More than one question or screen has the ref %s", ref))
+		}
+		refs[ref] = true
+	}
+
+	for _, s := range form.WelcomeScreens {
+		checkRef("welcome screen", s.Ref)
+	}
+	for _, f := range flattenFields(form.Fields) {
+		checkRef("question", f.Ref)
+	}
+	for _, s := range form.ThankYouScreens {
+		checkRef("thankyou screen", s.Ref)
+	}
+
+	for _, f := range form.Fields {
+		problems = append(problems, checkFieldDefinition(f, "")...)
+	}
+
+	for name := range form.Variables {
+		if !allows(logicVariables, name) {
+			problems = append(problems, fmt.Sprintf("The variable should be one of %s, got: %s", strings.Join(logicVariables, ", "), name))
+		}
+	}
+
+	if s := form.Settings; s != nil && s.Language != "" {
+		if _, err := parseLanguage(s.Language); err != nil {
+			problems = append(problems, fmt.Sprintf("Settings: language: %s", err))
+		}
+	}
+
+	problems = append(problems, checkLogicDefinition(form)...)
+
+	if err := CheckRecall(form); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	return problems
+}
+
+// definitionFiles are the file at the path, or the
+// JSON and YAML files of the directory at the path
+func definitionFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, e := range entries {
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".json", ".yaml", ".yml":
+			if !e.IsDir() {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+	}
+	return files, nil
+}
+
+// LoadDefinitions reads the form definition at the path, or every form
+// definition in the directory at the path, by file name. The workspace,
+// if any, is used over the workspace of the definitions. Forms with a
+// problem are left out, and their problems are in the report.
+func LoadDefinitions(workspace, path string) (map[string]*FormConf, ErrorReport, error) {
+	files, err := definitionFiles(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	formConfs := map[string]*FormConf{}
+	report := ErrorReport{}
+	for _, file := range files {
+		d := new(FormDefinition)
+		err := readDefinition(file, d)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		form := &d.Form
+		if workspace != "" {
+			form.Workspace = Workspace{fmt.Sprintf("https://api.typeform.com/workspaces/%s", workspace)}
+		}
+		if form.Workspace.Href == "" {
+			return nil, nil, fmt.Errorf("Form %s has no workspace, give one with --workspace", file)
+		}
+
+		messagesData := d.messagesData()
+
+		problems := formProblems(form)
+		if len(problems) == 0 {
+			for _, e := range messagesRecallReport(form, messagesData) {
+				problems = append(problems, e.Message)
+			}
+		}
+		if len(problems) > 0 {
+			for _, p := range problems {
+				report = append(report, &RowError{File: filepath.Base(file), Message: p})
+			}
+			continue
+		}
+
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		formConfs[name] = &FormConf{
+			Name:         form.Title,
+			Form:         form,
+			MessagesData: messagesData,
+			Dir:          filepath.Dir(file),
+			Sheet:        name,
+		}
+	}
+
+	return formConfs, report, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const yamlDefinition = `
+title: Consent
+fields:
+  - ref: consent
+    type: multiple_choice
+    title: Do you agree?
+    properties:
+      choices:
+        - {label: "Yes", ref: "yes"}
+        - {label: "No", ref: "no"}
+    validations:
+      required: true
+  - ref: name
+    type: short_text
+    title: What is your name?
+  - ref: bye
+    type: statement
+    title: Thanks {{field:name}}!
+thankyou_screens:
+  - ref: optout
+    title: Ok, no problem
+logic:
+  - type: field
+    ref: consent
+    actions:
+      - action: jump
+        details: {to: {type: thankyou, value: optout}}
+        condition:
+          op: is
+          vars: [{type: field, value: consent}, {type: choice, value: "no"}]
+messages:
+  label.buttonHint.default: Press Enter
+`
+
+func writeDefinition(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadDefinitions_ReadsYAMLForms(t *testing.T) {
+	path := writeDefinition(t, t.TempDir(), "consent.yaml", yamlDefinition)
+
+	formConfs, report, err := LoadDefinitions("work", path)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(report))
+
+	conf := formConfs["consent"]
+	assert.Equal(t, "Consent", conf.Name)
+	assert.Equal(t, "https://api.typeform.com/workspaces/work", conf.Form.Workspace.Href)
+	assert.Equal(t, 3, len(conf.Form.Fields))
+	assert.Equal(t, "no", conf.Form.Fields[0].Properties.Choices[1].Ref)
+	assert.True(t, conf.Form.Fields[0].Validations.Required)
+	assert.Equal(t, [][]string{{"variable", "message"}, {"label.buttonHint.default", "Press Enter"}}, conf.MessagesData)
+	assert.JSONEq(t, `[{"type":"field","ref":"consent","actions":[{"action":"jump","details":{"to":{"type":"thankyou","value":"optout"}},"condition":{"op":"is","vars":[{"type":"field","value":"consent"},{"type":"choice","value":"no"}]}}]}]`, string(conf.Form.Logic))
+}
+
+func TestLoadDefinitions_ReadsEveryFileOfDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeDefinition(t, dir, "consent.yaml", yamlDefinition)
+	writeDefinition(t, dir, "name.json", `{"title": "Name", "workspace": {"href": "https://api.typeform.com/workspaces/other"}, "fields": [{"ref": "name", "type": "short_text", "title": "Name?"}]}`)
+	writeDefinition(t, dir, "notes.txt", "not a form")
+
+	_, _, err := LoadDefinitions("", dir)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "has no workspace")
+
+	formConfs, _, err := LoadDefinitions("work", dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(formConfs))
+	assert.Equal(t, "Name", formConfs["name"].Name)
+	assert.Equal(t, "https://api.typeform.com/workspaces/work", formConfs["name"].Form.Workspace.Href)
+}
+
+func TestLoadDefinitions_ReportsFormsWithProblems(t *testing.T) {
+	dir := t.TempDir()
+	writeDefinition(t, dir, "consent.yaml", yamlDefinition)
+	writeDefinition(t, dir, "name.json", `{"title": "Name", "fields": [{"ref": "name", "type": "short_text", "title": "Hi {{field:nmae}}"}, {"ref": "age", "type": "number"}]}`)
+
+	formConfs, report, err := LoadDefinitions("work", dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(formConfs))
+	assert.NotNil(t, formConfs["consent"])
+
+	assert.Equal(t, 2, len(report))
+	for _, e := range report {
+		assert.Equal(t, "name.json", e.File)
+		assert.Equal(t, 0, e.Row)
+	}
+
+	_, err = checkReport(report, "", true)
+	assert.NotNil(t, err)
+}
+
+func TestValidateForm_ReportsEveryProblem(t *testing.T) {
+	form := &Form{
+		Title: "Broken",
+		Fields: []*Field{
+			{Ref: "consent", Type: "multiple_choice", Title: "Agree?"},
+			{Ref: "name", Type: "shortest_text", Title: "Name?"},
+			{Ref: "name", Type: "statement", Title: "Hi {{field:nmae}}"},
+			{Ref: "group", Type: "group", Title: "Group", Properties: &FieldProperties{Fields: []*Field{
+				{Ref: "inner", Type: "group", Title: "Inner"},
+			}}},
+		},
+		Logic:     []byte(`[{"type":"field","ref":"name","actions":[{"action":"jump","details":{"to":{"type":"field","value":"consent"}},"condition":{"op":"always","vars":[]}}]}]`),
+		Variables: map[string]float64{"points": 0},
+	}
+
+	err := ValidateForm(form)
+	assert.NotNil(t, err)
+	assert.Equal(t, `More than one question or screen has the ref name
+Question consent: multiple_choice questions need choices
+Question name: unknown type shortest_text
+Question inner: the group cannot be put inside of another group (group)
+The variable should be one of score, price, got: points
+Logic: question name cannot jump back to consent
+Question name recalls question nmae, which is not in the form`, err.Error())
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Field mirrors trans.Field, but carries the type-specific
+// properties that Typeform accepts and trans doesn't know about.
+type Field struct {
+	ID          string            `json:"id,omitempty"`
+	Type        string            `json:"type,omitempty"`
+	Title       string            `json:"title,omitempty"`
+	Ref         string            `json:"ref,omitempty"`
+	Properties  *FieldProperties  `json:"properties,omitempty"`
+	Validations *FieldValidations `json:"validations,omitempty"`
+	Attachment  *Attachment       `json:"attachment,omitempty"`
+	Layout      *Layout           `json:"layout,omitempty"`
+}
+
+type FieldChoice struct {
+	ID         string      `json:"id,omitempty"`
+	Label      string      `json:"label,omitempty"`
+	Ref        string      `json:"ref,omitempty"`
+	Attachment *Attachment `json:"attachment,omitempty"`
+
+	// added to the score variable when chosen
+	Score float64 `json:"-"`
+}
+
+// extractPictureChoices reads the options of a picture_choice
+// question, one "label | image path or URL" per line.
+func extractPictureChoices(options string) ([]*FieldChoice, error) {
+	choices := []*FieldChoice{}
+	for _, line := range ExtractParagraphs(options) {
+		parts := strings.SplitN(line, "|", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("Picture choice options should look like label | image, got: %s", line)
+		}
+
+		choices = append(choices, &FieldChoice{
+			Label:      strings.TrimSpace(parts[0]),
+			Attachment: imageAttachment(parts[1]),
+		})
+	}
+	return choices, nil
+}
+
+type FieldLabels struct {
+	Left   string `json:"left,omitempty"`
+	Center string `json:"center,omitempty"`
+	Right  string `json:"right,omitempty"`
+}
+
+type FieldProperties struct {
+	Choices            []*FieldChoice `json:"choices,omitempty"`
+	Description        string         `json:"description,omitempty"`
+	Steps              int            `json:"steps,omitempty"`
+	StartAtOne         bool           `json:"start_at_one,omitempty"`
+	Labels             *FieldLabels   `json:"labels,omitempty"`
+	Shape              string         `json:"shape,omitempty"`
+	Separator          string         `json:"separator,omitempty"`
+	Structure          string         `json:"structure,omitempty"`
+	DefaultCountryCode string         `json:"default_country_code,omitempty"`
+	ButtonText         string         `json:"button_text,omitempty"`
+	HideMarks          bool           `json:"hide_marks,omitempty"`
+	ShowButton         bool           `json:"show_button,omitempty"`
+	Fields             []*Field       `json:"fields,omitempty"`
+
+	// choice flags are pointers so that a translation
+	// can leave them unset and inherit them from the base
+	Randomize              *bool `json:"randomize,omitempty"`
+	AllowMultipleSelection *bool `json:"allow_multiple_selection,omitempty"`
+	AllowOtherChoice       *bool `json:"allow_other_choice,omitempty"`
+	VerticalAlignment      *bool `json:"vertical_alignment,omitempty"`
+	AlphabeticalOrder      *bool `json:"alphabetical_order,omitempty"`
+
+	Supersize  bool  `json:"supersize,omitempty"`
+	ShowLabels *bool `json:"show_labels,omitempty"`
+}
+
+var choiceFlagNames = []string{
+	"randomize",
+	"allow_multiple_selection",
+	"allow_other_choice",
+	"vertical_alignment",
+	"alphabetical_order",
+}
+
+// choiceFlags lists the choice flags of the properties by name
+func (p *FieldProperties) choiceFlags() map[string]**bool {
+	return map[string]**bool{
+		"randomize":                &p.Randomize,
+		"allow_multiple_selection": &p.AllowMultipleSelection,
+		"allow_other_choice":       &p.AllowOtherChoice,
+		"vertical_alignment":       &p.VerticalAlignment,
+		"alphabetical_order":       &p.AlphabeticalOrder,
+	}
+}
+
+func isSet(b *bool) bool {
+	return b != nil && *b
+}
+
+func (p *FieldProperties) labels() *FieldLabels {
+	if p.Labels == nil {
+		p.Labels = &FieldLabels{}
+	}
+	return p.Labels
+}
+
+type fieldSetter func(f *Field, value string) error
+
+func stringSetter(set func(*Field, string)) fieldSetter {
+	return func(f *Field, value string) error {
+		set(f, value)
+		return nil
+	}
+}
+
+func intSetter(set func(*Field, int)) fieldSetter {
+	return func(f *Field, value string) error {
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("expected a whole number, got: %s", value)
+		}
+		set(f, i)
+		return nil
+	}
+}
+
+// parseBool reads true/false and yes/no values
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "yes", "y", "true", "1":
+		return true, nil
+	case "no", "n", "false", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected yes or no, got: %s", value)
+}
+
+func boolSetter(set func(*Field, bool)) fieldSetter {
+	return func(f *Field, value string) error {
+		b, err := parseBool(value)
+		if err != nil {
+			return err
+		}
+		set(f, b)
+		return nil
+	}
+}
+
+func flagSetter(name string) fieldSetter {
+	return boolSetter(func(f *Field, v bool) { *f.Properties.choiceFlags()[name] = &v })
+}
+
+var propertySetters = map[string]fieldSetter{
+	"randomize":                flagSetter("randomize"),
+	"allow_multiple_selection": flagSetter("allow_multiple_selection"),
+	"allow_other_choice":       flagSetter("allow_other_choice"),
+	"vertical_alignment":       flagSetter("vertical_alignment"),
+	"alphabetical_order":       flagSetter("alphabetical_order"),
+	"steps":                    intSetter(func(f *Field, v int) { f.Properties.Steps = v }),
+	"start_at_one":             boolSetter(func(f *Field, v bool) { f.Properties.StartAtOne = v }),
+	"labels.left":              stringSetter(func(f *Field, v string) { f.Properties.labels().Left = v }),
+	"labels.center":            stringSetter(func(f *Field, v string) { f.Properties.labels().Center = v }),
+	"labels.right":             stringSetter(func(f *Field, v string) { f.Properties.labels().Right = v }),
+	"shape":                    stringSetter(func(f *Field, v string) { f.Properties.Shape = v }),
+	"separator":                stringSetter(func(f *Field, v string) { f.Properties.Separator = v }),
+	"structure":                stringSetter(func(f *Field, v string) { f.Properties.Structure = v }),
+	"default_country_code":     stringSetter(func(f *Field, v string) { f.Properties.DefaultCountryCode = v }),
+	"button_text":              stringSetter(func(f *Field, v string) { f.Properties.ButtonText = v }),
+	"hide_marks":               boolSetter(func(f *Field, v bool) { f.Properties.HideMarks = v }),
+	"show_button":              boolSetter(func(f *Field, v bool) { f.Properties.ShowButton = v }),
+	"supersize":                boolSetter(func(f *Field, v bool) { f.Properties.Supersize = v }),
+	"show_labels":              boolSetter(func(f *Field, v bool) { f.Properties.ShowLabels = &v }),
+}
+
+var scaleLabels = []string{"labels.left", "labels.center", "labels.right"}
+
+// Known field types, and the properties that
+// may be set on each from the properties column.
+var fieldTypeProperties = map[string][]string{
+	"short_text":      {},
+	"long_text":       {},
+	"statement":       {"button_text", "hide_marks"},
+	"group":           {"button_text", "show_button"},
+	"matrix":          {},
+	"multiple_choice": {"randomize", "allow_multiple_selection", "allow_other_choice", "vertical_alignment"},
+	"picture_choice":  {"randomize", "allow_multiple_selection", "allow_other_choice", "supersize", "show_labels"},
+	"dropdown":        {"randomize", "alphabetical_order"},
+	"ranking":         {"randomize"},
+	"yes_no":          {},
+	"email":           {},
+	"number":          {},
+	"website":         {},
+	"legal":           {},
+	"file_upload":     {},
+	"opinion_scale":   append([]string{"steps", "start_at_one"}, scaleLabels...),
+	"nps":             scaleLabels,
+	"rating":          {"steps", "shape"},
+	"date":            {"separator", "structure"},
+	"phone_number":    {"default_country_code"},
+}
+
+// Field types whose options column holds the choices
+var choiceTypes = map[string]bool{
+	"multiple_choice": true,
+	"dropdown":        true,
+	"ranking":         true,
+}
+
+func allows(allowed []string, key string) bool {
+	for _, a := range allowed {
+		if a == key {
+			return true
+		}
+	}
+	return false
+}
+
+type keyValue struct {
+	Key   string
+	Value string
+}
+
+// parseKeyValues splits a cell such as "a=1; b=2" into its pairs
+func parseKeyValues(cell string) ([]keyValue, error) {
+	pairs := []keyValue{}
+
+	for _, pair := range strings.Split(cell, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Expected key=value, got: %s", pair)
+		}
+
+		pairs = append(pairs, keyValue{strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])})
+	}
+
+	return pairs, nil
+}
+
+// applyKeyValues sets every key=value pair in the cell on the field,
+// using the setters and the per-type table of allowed keys.
+func applyKeyValues(kind string, f *Field, cell string, setters map[string]fieldSetter, allowedByType map[string][]string) error {
+	pairs, err := parseKeyValues(cell)
+	if err != nil {
+		return err
+	}
+
+	allowed, known := allowedByType[f.Type]
+
+	for _, kv := range pairs {
+		if !known {
+			return fmt.Errorf("Cannot set %s %s on unknown question type: %s", kind, kv.Key, f.Type)
+		}
+
+		if !allows(allowed, kv.Key) {
+			if len(allowed) == 0 {
+				return fmt.Errorf("The %s %s does not apply to %s questions, which take none", kind, kv.Key, f.Type)
+			}
+			sorted := append([]string{}, allowed...)
+			sort.Strings(sorted)
+			return fmt.Errorf("The %s %s does not apply to %s questions. Allowed: %s", kind, kv.Key, f.Type, strings.Join(sorted, ", "))
+		}
+
+		err := setters[kv.Key](f, kv.Value)
+		if err != nil {
+			return fmt.Errorf("Bad value for %s %s: %w", kind, kv.Key, err)
+		}
+	}
+
+	return nil
+}
+
+// ParseProperties sets the properties in a properties cell, such as
+// "steps=5; start_at_one=true; labels.left=Bad", on the field.
+func ParseProperties(f *Field, cell string) error {
+	return applyKeyValues("property", f, cell, propertySetters, fieldTypeProperties)
+}
+
+// flattenFields lists the fields along with every field nested
+// inside of them (i.e. the questions of a group), in form order.
+func flattenFields(fields []*Field) []*Field {
+	flat := []*Field{}
+	for _, f := range fields {
+		flat = append(flat, f)
+		if f.Properties != nil {
+			flat = append(flat, flattenFields(f.Properties.Fields)...)
+		}
+	}
+	return flat
+}
+
+// addToGroup nests the field inside of the group (or matrix) with the
+// given ref. Groups cannot be nested in other groups and the rows of a
+// matrix must be multiple_choice questions.
+func addToGroup(fields []*Field, groupRef string, f *Field) error {
+	if f.Type == "group" || f.Type == "matrix" {
+		return fmt.Errorf("The %s %s cannot be put inside of another group (%s)", f.Type, f.Ref, groupRef)
+	}
+
+	for _, g := range fields {
+		if g.Ref != groupRef {
+			continue
+		}
+
+		if g.Type != "group" && g.Type != "matrix" {
+			return fmt.Errorf("Question %s is put in group %s, but %s is a %s question, not a group", f.Ref, groupRef, groupRef, g.Type)
+		}
+
+		if g.Type == "matrix" && f.Type != "multiple_choice" {
+			return fmt.Errorf("Question %s is a row of matrix %s, so it must be multiple_choice, not %s", f.Ref, groupRef, f.Type)
+		}
+
+		g.Properties.Fields = append(g.Properties.Fields, f)
+		return nil
+	}
+
+	return fmt.Errorf("Question %s is put in group %s, but there is no group with that ref before it", f.Ref, groupRef)
+}
+
+// question types that can score their choices
+var scoredTypes = []string{"multiple_choice", "dropdown", "picture_choice"}
+
+// ParseScores sets the score of each choice of the field from a
+// score cell, such as "Yes=1; No=0", by choice label.
+func ParseScores(f *Field, cell string) error {
+	pairs, err := parseKeyValues(cell)
+	if err != nil {
+		return err
+	}
+
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	if !allows(scoredTypes, f.Type) {
+		return fmt.Errorf("%s questions cannot have scores", f.Type)
+	}
+
+	for _, kv := range pairs {
+		score, err := strconv.ParseFloat(kv.Value, 64)
+		if err != nil {
+			return fmt.Errorf("The score of %s should be a number, got: %s", kv.Key, kv.Value)
+		}
+
+		found := false
+		for _, c := range f.Properties.Choices {
+			if strings.EqualFold(c.Label, kv.Key) {
+				c.Score = score
+				found = true
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("Cannot score %s, it is not a choice", kv.Key)
+		}
+	}
+
+	return nil
+}
@@ -41,5 +41,5 @@ require (
 	github.com/dghubble/sling v1.4.0
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/vlab-research/trans v0.0.12
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
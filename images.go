@@ -0,0 +1,173 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+type Image struct {
+	ID       string `json:"id,omitempty"`
+	Src      string `json:"src,omitempty"`
+	FileName string `json:"file_name,omitempty"`
+}
+
+type imageUpload struct {
+	Image    string `json:"image,omitempty"`
+	Url      string `json:"url,omitempty"`
+	FileName string `json:"file_name"`
+}
+
+func isTypeformImage(href string) bool {
+	return strings.HasPrefix(href, "https://images.typeform.com/")
+}
+
+func isUrl(href string) bool {
+	return strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://")
+}
+
+func (t *TypeformUploader) GetImages() ([]*Image, error) {
+	api := t.Api()
+
+	apiError := new(TypeformError)
+	images := []*Image{}
+
+	_, err := api.New().Get("images").Receive(&images, apiError)
+	if err != nil {
+		return nil, err
+	}
+
+	if !apiError.Empty() {
+		return nil, apiError
+	}
+	return images, nil
+}
+
+func (t *TypeformUploader) uploadImage(upload *imageUpload) (*Image, error) {
+	api := t.Api()
+
+	apiError := new(TypeformError)
+	image := new(Image)
+
+	_, err := api.New().Post("images").BodyJSON(upload).Receive(image, apiError)
+	if err != nil {
+		return nil, err
+	}
+
+	if !apiError.Empty() {
+		return nil, apiError
+	}
+	return image, nil
+}
+
+// imageFileName names uploads after a hash of their content (or of the
+// url, for remote images) so that we can tell if they were uploaded before.
+func imageFileName(content []byte, path string) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]) + strings.ToLower(filepath.Ext(path))
+}
+
+// UploadImage uploads a local image (relative to dir) or a remote image
+// to Typeform and returns its Typeform href. Images that were already
+// uploaded, in this run or before, are not uploaded again.
+func (t *TypeformUploader) UploadImage(dir, href string) (string, error) {
+	if t.images == nil {
+		images, err := t.GetImages()
+		if err != nil {
+			return "", err
+		}
+
+		t.images = map[string]string{}
+		for _, i := range images {
+			t.images[i.FileName] = i.Src
+		}
+	}
+
+	upload := new(imageUpload)
+
+	if isUrl(href) {
+		upload.Url = href
+		upload.FileName = imageFileName([]byte(href), href)
+	} else {
+		path := href
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("Could not read image %s: %w", href, err)
+		}
+
+		upload.Image = base64.StdEncoding.EncodeToString(content)
+		upload.FileName = imageFileName(content, path)
+	}
+
+	if src, ok := t.images[upload.FileName]; ok {
+		return src, nil
+	}
+
+	image, err := t.uploadImage(upload)
+	if err != nil {
+		return "", err
+	}
+
+	t.images[upload.FileName] = image.Src
+	return image.Src, nil
+}
+
+// formImages lists every image attachment in the form
+func formImages(form *Form) []*Attachment {
+	attachments := []*Attachment{}
+
+	for _, f := range flattenFields(form.Fields) {
+		attachments = append(attachments, f.Attachment)
+		if f.Layout != nil {
+			attachments = append(attachments, f.Layout.Attachment)
+		}
+
+		if f.Properties == nil {
+			continue
+		}
+		for _, c := range f.Properties.Choices {
+			attachments = append(attachments, c.Attachment)
+		}
+	}
+
+	for _, s := range form.WelcomeScreens {
+		attachments = append(attachments, s.Attachment)
+	}
+
+	for _, s := range form.ThankYouScreens {
+		attachments = append(attachments, s.Attachment)
+	}
+
+	images := []*Attachment{}
+	for _, a := range attachments {
+		if a != nil && a.Type == "image" {
+			images = append(images, a)
+		}
+	}
+	return images
+}
+
+// UploadImages uploads every image in the form that isn't
+// hosted by Typeform yet and points the form to the upload.
+func (t *TypeformUploader) UploadImages(conf *FormConf) error {
+	for _, a := range formImages(conf.Form) {
+		if a.Href == "" || isTypeformImage(a.Href) {
+			continue
+		}
+
+		src, err := t.UploadImage(conf.Dir, a.Href)
+		if err != nil {
+			return err
+		}
+		a.Href = src
+	}
+	return nil
+}
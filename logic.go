@@ -0,0 +1,378 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// header names of the Logic sheet, mapped to their canonical name
+var logicColumnAliases = map[string]string{
+	"form":     "form",
+	"sheet":    "form",
+	"field":    "field",
+	"ref":      "field",
+	"choice":   "choice",
+	"if":       "choice",
+	"answer":   "choice",
+	"jump_to":  "jump_to",
+	"to":       "jump_to",
+	"jump":     "jump_to",
+	"action":   "action",
+	"variable": "variable",
+	"value":    "value",
+}
+
+var requiredLogicColumns = []string{"field", "choice"}
+
+// the variables Typeform has
+var logicVariables = []string{"score", "price"}
+
+var calculations = []string{"add", "subtract", "multiply", "divide", "set"}
+
+type LogicValue struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+type LogicCondition struct {
+	Op   string        `json:"op"`
+	Vars []*LogicValue `json:"vars"`
+}
+
+type LogicDetails struct {
+	To     *LogicValue `json:"to,omitempty"`
+	Target *LogicValue `json:"target,omitempty"`
+	Value  *LogicValue `json:"value,omitempty"`
+}
+
+type LogicAction struct {
+	Action    string          `json:"action"`
+	Details   *LogicDetails   `json:"details"`
+	Condition *LogicCondition `json:"condition"`
+}
+
+type FieldLogic struct {
+	Type    string         `json:"type"`
+	Ref     string         `json:"ref"`
+	Actions []*LogicAction `json:"actions"`
+}
+
+func fieldIndex(ref string, fields []*Field) int {
+	for i, f := range fields {
+		if f.Ref == ref {
+			return i
+		}
+	}
+	return -1
+}
+
+// choiceRef finds the choice with the label in the field and returns
+// its ref, making one up if the choice doesn't have one yet.
+func choiceRef(f *Field, label string) (string, error) {
+	for i, c := range f.Properties.Choices {
+		if strings.EqualFold(strings.TrimSpace(c.Label), strings.TrimSpace(label)) {
+			if c.Ref == "" {
+				c.Ref = fmt.Sprintf("%s_%d", f.Ref, i+1)
+			}
+			return c.Ref, nil
+		}
+	}
+	return "", fmt.Errorf("Question %s has no choice %s", f.Ref, label)
+}
+
+func buildCondition(f *Field, choice string) (*LogicCondition, error) {
+	if strings.EqualFold(strings.TrimSpace(choice), "always") {
+		return &LogicCondition{Op: "always", Vars: []*LogicValue{}}, nil
+	}
+
+	var value *LogicValue
+
+	switch f.Type {
+	case "multiple_choice", "dropdown", "picture_choice":
+		ref, err := choiceRef(f, choice)
+		if err != nil {
+			return nil, err
+		}
+		value = &LogicValue{"choice", ref}
+
+	case "yes_no":
+		b, err := parseBool(choice)
+		if err != nil {
+			return nil, fmt.Errorf("Question %s is yes_no, so the choice should be yes or no, got: %s", f.Ref, choice)
+		}
+		value = &LogicValue{"constant", b}
+
+	default:
+		return nil, fmt.Errorf("Question %s is %s, only choice and yes_no questions can jump on an answer", f.Ref, f.Type)
+	}
+
+	return &LogicCondition{Op: "is", Vars: []*LogicValue{{"field", f.Ref}, value}}, nil
+}
+
+// buildJump builds the jump of one row of the Logic sheet. Jumps only go
+// forward, to a question or to a thankyou screen.
+func buildJump(form *Form, ref, choice, to string) (*LogicAction, error) {
+	fields := flattenFields(form.Fields)
+
+	from := fieldIndex(ref, fields)
+	if from == -1 {
+		return nil, fmt.Errorf("Could not find question %s", ref)
+	}
+
+	condition, err := buildCondition(fields[from], choice)
+	if err != nil {
+		return nil, err
+	}
+
+	target := &LogicValue{"field", to}
+
+	if i := fieldIndex(to, fields); i == -1 {
+		found := false
+		for _, s := range form.ThankYouScreens {
+			found = found || s.Ref == to
+		}
+		if !found {
+			return nil, fmt.Errorf("Could not find question or thankyou screen %s to jump to", to)
+		}
+		target.Type = "thankyou"
+	} else if i <= from {
+		return nil, fmt.Errorf("Question %s cannot jump back to %s", ref, to)
+	}
+
+	return &LogicAction{"jump", &LogicDetails{To: target}, condition}, nil
+}
+
+// buildCalculation builds an add/subtract/multiply/divide/set action
+// on a variable, done when the question gets the choice.
+func buildCalculation(form *Form, ref, choice, action, variable, value string) (*LogicAction, error) {
+	field, err := findField(ref, form)
+	if err != nil {
+		return nil, fmt.Errorf("Could not find question %s", ref)
+	}
+
+	if !allows(calculations, action) {
+		return nil, fmt.Errorf("The action should be jump or one of %s, got: %s", strings.Join(calculations, ", "), action)
+	}
+
+	if !allows(logicVariables, variable) {
+		return nil, fmt.Errorf("The variable should be one of %s, got: %s", strings.Join(logicVariables, ", "), variable)
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return nil, fmt.Errorf("The value of a %s should be a number, got: %s", action, value)
+	}
+
+	condition, err := buildCondition(field, choice)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := form.Variables[variable]; !ok {
+		if form.Variables == nil {
+			form.Variables = map[string]float64{}
+		}
+		form.Variables[variable] = 0
+	}
+
+	details := &LogicDetails{
+		Target: &LogicValue{"variable", variable},
+		Value:  &LogicValue{"constant", n},
+	}
+	return &LogicAction{action, details, condition}, nil
+}
+
+// scoreActions adds to (or subtracts from) the score
+// for every choice with a score in the form.
+func scoreActions(form *Form, f *Field) ([]*LogicAction, error) {
+	actions := []*LogicAction{}
+
+	for _, c := range f.Properties.Choices {
+		if c.Score == 0 {
+			continue
+		}
+
+		action, value := "add", c.Score
+		if value < 0 {
+			action, value = "subtract", -value
+		}
+
+		a, err := buildCalculation(form, f.Ref, c.Label, action, "score", strconv.FormatFloat(value, 'f', -1, 64))
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, a)
+	}
+
+	return actions, nil
+}
+
+type fieldActions struct {
+	calculations []*LogicAction
+	jumps        []*LogicAction
+}
+
+// BuildLogic compiles the scores of the choices and the rows of the
+// Logic sheet that belong to the form's sheet into Typeform logic.
+// Without a form column, every row belongs to every form. Calculations
+// go before the jumps of a question. Jumps are checked in order, so an
+// "always" jump must be the last one for its question. Rows with a
+// problem are left out, and returned with the logic as a ReportError.
+func BuildLogic(form *Form, sheet string, records [][]string) (json.RawMessage, error) {
+	refs := []string{}
+	byRef := map[string]*fieldActions{}
+
+	actionsOf := func(ref string) *fieldActions {
+		fa, ok := byRef[ref]
+		if !ok {
+			fa = &fieldActions{}
+			byRef[ref] = fa
+			refs = append(refs, ref)
+		}
+		return fa
+	}
+
+	for _, f := range flattenFields(form.Fields) {
+		if f.Properties == nil {
+			continue
+		}
+
+		scores, err := scoreActions(form, f)
+		if err != nil {
+			return nil, err
+		}
+		if len(scores) > 0 {
+			fa := actionsOf(f.Ref)
+			fa.calculations = append(fa.calculations, scores...)
+		}
+	}
+
+	var cols Columns
+	report := ErrorReport{}
+	bad := func(row int, column string, err error) {
+		report = append(report, sheetError("Logic", cols, row, column, err))
+	}
+
+	if len(records) > 0 {
+		var err error
+		cols, err = parseColumns(records[0], logicColumnAliases, requiredLogicColumns)
+		if err != nil {
+			bad(1, "", err)
+			records = nil
+		}
+	}
+
+	for i := 1; i < len(records); i++ {
+		row := records[i]
+		ref := cols.Get(row, "field")
+		choice := cols.Get(row, "choice")
+		to := cols.Get(row, "jump_to")
+		action := strings.ToLower(strings.TrimSpace(cols.Get(row, "action")))
+
+		if ref == "" && choice == "" && to == "" && action == "" {
+			continue
+		}
+
+		if _, ok := cols["form"]; ok && cols.Get(row, "form") != sheet {
+			continue
+		}
+
+		if action != "" && action != "jump" {
+			if to != "" {
+				bad(i+1, "jump_to", fmt.Errorf("a %s row cannot also jump", action))
+				continue
+			}
+
+			a, err := buildCalculation(form, ref, choice, action, cols.Get(row, "variable"), cols.Get(row, "value"))
+			if err != nil {
+				bad(i+1, "", err)
+				continue
+			}
+
+			fa := actionsOf(ref)
+			fa.calculations = append(fa.calculations, a)
+			continue
+		}
+
+		if to == "" {
+			bad(i+1, "jump_to", fmt.Errorf("a jump needs a jump_to"))
+			continue
+		}
+
+		a, err := buildJump(form, ref, choice, to)
+		if err != nil {
+			bad(i+1, "", err)
+			continue
+		}
+
+		fa := actionsOf(ref)
+		if n := len(fa.jumps); n > 0 && fa.jumps[n-1].Condition.Op == "always" {
+			bad(i+1, "", fmt.Errorf("question %s already always jumps, put the always row last", ref))
+			continue
+		}
+		fa.jumps = append(fa.jumps, a)
+	}
+
+	// questions can be left with no actions by their bad rows
+	logic := []*FieldLogic{}
+	for _, ref := range refs {
+		fa := byRef[ref]
+		if len(fa.calculations)+len(fa.jumps) > 0 {
+			logic = append(logic, &FieldLogic{Type: "field", Ref: ref, Actions: append(fa.calculations, fa.jumps...)})
+		}
+	}
+
+	if len(logic) == 0 {
+		return nil, report.asError()
+	}
+
+	b, err := json.Marshal(logic)
+	if err != nil {
+		return nil, err
+	}
+	return b, report.asError()
+}
+
+// ParseVariables reads the Variables sheet, a name and a starting
+// value per row, into the variables of a form. Rows with a problem
+// are left out, and returned as a ReportError.
+func ParseVariables(records [][]string) (map[string]float64, error) {
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	variables := map[string]float64{}
+	cols := Columns{"variable": 0, "value": 1}
+	report := ErrorReport{}
+
+	for i, r := range records[1:] {
+		name := strings.TrimSpace(get(r, 0))
+		value := strings.TrimSpace(get(r, 1))
+
+		if name == "" && value == "" {
+			continue
+		}
+
+		if !allows(logicVariables, name) {
+			err := fmt.Errorf("the variable should be one of %s, got: %s", strings.Join(logicVariables, ", "), name)
+			report = append(report, sheetError("Variables", cols, i+2, "variable", err))
+			continue
+		}
+
+		n := 0.0
+		if value != "" {
+			var err error
+			n, err = strconv.ParseFloat(value, 64)
+			if err != nil {
+				err = fmt.Errorf("the value should be a number, got: %s", value)
+				report = append(report, sheetError("Variables", cols, i+2, "value", err))
+				continue
+			}
+		}
+		variables[name] = n
+	}
+
+	return variables, report.asError()
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func logicForm() *Form {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "options"})
+	form, _, _ := BuildForm("foo", cols, nil, [][]string{
+		{"consent", "multiple_choice", "Do you consent?", "Yes\nNo"},
+		{"likes", "yes_no", "Do you like it?"},
+		{"name", "short_text", "Name?"},
+		{"bye", "statement", "Bye"},
+		{"optout", "thankyou_screen", "Ok, no problem"},
+	})
+	return form
+}
+
+func TestBuildLogic_CompilesJumps(t *testing.T) {
+	form := logicForm()
+
+	records := [][]string{
+		{"field", "choice", "jump_to"},
+		{"consent", "No", "optout"},
+		{"consent", "always", "likes"},
+		{"likes", "no", "bye"},
+	}
+
+	logic, err := BuildLogic(form, "Sheet1", records)
+	assert.Nil(t, err)
+
+	expected := `[
+{"type":"field","ref":"consent","actions":[
+  {"action":"jump","details":{"to":{"type":"thankyou","value":"optout"}},"condition":{"op":"is","vars":[{"type":"field","value":"consent"},{"type":"choice","value":"consent_2"}]}},
+  {"action":"jump","details":{"to":{"type":"field","value":"likes"}},"condition":{"op":"always","vars":[]}}]},
+{"type":"field","ref":"likes","actions":[
+  {"action":"jump","details":{"to":{"type":"field","value":"bye"}},"condition":{"op":"is","vars":[{"type":"field","value":"likes"},{"type":"constant","value":false}]}}]}
+]`
+	assert.JSONEq(t, expected, string(logic))
+
+	// the choice gets the ref used in the logic
+	assert.Equal(t, "consent_2", form.Fields[0].Properties.Choices[1].Ref)
+	assert.Equal(t, "", form.Fields[0].Properties.Choices[0].Ref)
+}
+
+func TestBuildLogic_UsesExistingChoiceRefs(t *testing.T) {
+	form := logicForm()
+	form.Fields[0].Properties.Choices[1].Ref = "01FN245E7DSR7FAFD27B7SF7YS"
+
+	logic, err := BuildLogic(form, "Sheet1", [][]string{
+		{"field", "choice", "jump_to"},
+		{"consent", "no", "optout"},
+	})
+	assert.Nil(t, err)
+
+	parsed := []*FieldLogic{}
+	json.Unmarshal(logic, &parsed)
+	assert.Equal(t, "01FN245E7DSR7FAFD27B7SF7YS", parsed[0].Actions[0].Condition.Vars[1].Value)
+}
+
+func TestBuildLogic_OnlyUsesRowsOfTheForm(t *testing.T) {
+	form := logicForm()
+
+	logic, err := BuildLogic(form, "Baseline", [][]string{
+		{"form", "field", "choice", "jump_to"},
+		{"Endline", "foo", "bar", "baz"},
+		{"Baseline", "consent", "No", "optout"},
+	})
+	assert.Nil(t, err)
+
+	parsed := []*FieldLogic{}
+	json.Unmarshal(logic, &parsed)
+	assert.Equal(t, 1, len(parsed))
+
+	logic, err = BuildLogic(form, "Other", [][]string{
+		{"form", "field", "choice", "jump_to"},
+		{"Baseline", "consent", "No", "optout"},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, logic)
+}
+
+func TestBuildLogic_RaisesOnBadRows(t *testing.T) {
+	bad := [][]string{
+		{"nope", "No", "optout"},
+		{"consent", "Maybe", "optout"},
+		{"consent", "No", "nope"},
+		{"likes", "No", "consent"},
+		{"likes", "perhaps", "bye"},
+		{"name", "Bob", "bye"},
+	}
+
+	for _, row := range bad {
+		_, err := BuildLogic(logicForm(), "Sheet1", [][]string{{"field", "choice", "jump_to"}, row})
+		assert.NotNil(t, err, row)
+		assert.Contains(t, err.Error(), "row 2")
+	}
+
+	_, err := BuildLogic(logicForm(), "Sheet1", [][]string{
+		{"field", "choice", "jump_to"},
+		{"consent", "always", "likes"},
+		{"consent", "No", "optout"},
+	})
+	assert.NotNil(t, err)
+}
+
+func TestBuildLogic_CompilesScoresBeforeJumps(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "options", "score"})
+	form, _, _ := BuildForm("foo", cols, nil, [][]string{
+		{"capital", "multiple_choice", "Capital of France?", "Paris\nLyon\nNice", "Paris=2; nice=-1"},
+		{"name", "short_text", "Name?"},
+	})
+
+	logic, err := BuildLogic(form, "Sheet1", [][]string{
+		{"field", "choice", "jump_to"},
+		{"capital", "always", "name"},
+	})
+	assert.Nil(t, err)
+
+	expected := `[
+{"type":"field","ref":"capital","actions":[
+  {"action":"add","details":{"target":{"type":"variable","value":"score"},"value":{"type":"constant","value":2}},"condition":{"op":"is","vars":[{"type":"field","value":"capital"},{"type":"choice","value":"capital_1"}]}},
+  {"action":"subtract","details":{"target":{"type":"variable","value":"score"},"value":{"type":"constant","value":1}},"condition":{"op":"is","vars":[{"type":"field","value":"capital"},{"type":"choice","value":"capital_3"}]}},
+  {"action":"jump","details":{"to":{"type":"field","value":"name"}},"condition":{"op":"always","vars":[]}}]}
+]`
+	assert.JSONEq(t, expected, string(logic))
+	assert.Equal(t, map[string]float64{"score": 0}, form.Variables)
+}
+
+func TestBuildLogic_CompilesCalculationRows(t *testing.T) {
+	form := logicForm()
+	form.Variables = map[string]float64{"price": 10}
+
+	logic, err := BuildLogic(form, "Sheet1", [][]string{
+		{"field", "choice", "action", "variable", "value"},
+		{"likes", "yes", "multiply", "price", "1.5"},
+	})
+	assert.Nil(t, err)
+
+	expected := `[
+{"type":"field","ref":"likes","actions":[
+  {"action":"multiply","details":{"target":{"type":"variable","value":"price"},"value":{"type":"constant","value":1.5}},"condition":{"op":"is","vars":[{"type":"field","value":"likes"},{"type":"constant","value":true}]}}]}
+]`
+	assert.JSONEq(t, expected, string(logic))
+	assert.Equal(t, map[string]float64{"price": 10}, form.Variables)
+}
+
+func TestBuildLogic_ErrorsOnBadCalculations(t *testing.T) {
+	rows := [][]string{
+		{"likes", "yes", "add", "points", "1", ""},
+		{"likes", "yes", "add", "score", "one", ""},
+		{"likes", "yes", "square", "score", "1", ""},
+		{"likes", "yes", "add", "score", "1", "bye"},
+		{"likes", "yes", "", "", "", ""},
+	}
+
+	for _, row := range rows {
+		_, err := BuildLogic(logicForm(), "Sheet1", [][]string{
+			{"field", "choice", "action", "variable", "value", "jump_to"},
+			row,
+		})
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "Logic sheet, row 2")
+	}
+}
+
+func TestParseVariables(t *testing.T) {
+	variables, err := ParseVariables([][]string{
+		{"variable", "value"},
+		{"score", ""},
+		{},
+		{"price", "9.99"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]float64{"score": 0, "price": 9.99}, variables)
+
+	_, err = ParseVariables([][]string{{"variable", "value"}, {"points", "0"}})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "row 2")
+}
@@ -13,7 +13,10 @@ import (
 
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 )
 
@@ -23,20 +26,29 @@ func handle(err error) {
 	}
 }
 
-func readCsvFile(filePath string) [][]string {
+func readCsvFile(filePath string) ([][]string, error) {
 	f, err := os.Open(filePath)
 	if err != nil {
-		log.Fatal("Unable to read input file "+filePath, err)
+		return nil, fmt.Errorf("Unable to read input file %s: %w", filePath, err)
 	}
 	defer f.Close()
 
 	csvReader := csv.NewReader(f)
+
+	// rows can be shorter than the header, like in Excel
+	csvReader.FieldsPerRecord = -1
+
 	records, err := csvReader.ReadAll()
 	if err != nil {
-		log.Fatal("Unable to parse file as CSV for "+filePath, err)
+		return nil, fmt.Errorf("Unable to parse file as CSV for %s: %w", filePath, err)
+	}
+
+	// files saved from Excel start with a byte order mark
+	if len(records) > 0 && len(records[0]) > 0 {
+		records[0][0] = strings.TrimPrefix(records[0][0], "\ufeff")
 	}
 
-	return records
+	return records, nil
 }
 
 func get(row []string, i int) string {
@@ -50,11 +62,11 @@ func ExtractParagraphs(text string) []string {
 	return strings.Split(strings.TrimSpace(text), "\n")
 }
 
-func extractChoices(options string) []*trans.FieldChoice {
+func extractChoices(options string) []*FieldChoice {
 	s := ExtractParagraphs(options)
-	choices := make([]*trans.FieldChoice, len(s))
+	choices := make([]*FieldChoice, len(s))
 	for i, ss := range s {
-		choices[i] = &trans.FieldChoice{
+		choices[i] = &FieldChoice{
 			ID:    "",
 			Label: ss,
 			Ref:   "",
@@ -63,36 +75,57 @@ func extractChoices(options string) []*trans.FieldChoice {
 	return choices
 }
 
-func BuildField(row []string) (interface{}, error) {
-	if len(row) < 3 {
-		return nil, fmt.Errorf("This row doesn't have the right number of columns: %s", row)
+// row types that are not questions
+var screenTypes = map[string]bool{
+	"welcome_screen":  true,
+	"thankyou_screen": true,
+	"hidden":          true,
+}
+
+func BuildField(cols Columns, row []string) (interface{}, error) {
+	ref := cols.Get(row, "ref")
+	questionType := cols.Get(row, "type")
+	q := cols.Get(row, "question")
+
+	if ref == "" {
+		return nil, atColumn("ref", fmt.Errorf("This row has no ref and will be skipped: %s", row))
 	}
 
-	ref := row[0]
-	questionType := row[1]
-	q := row[2]
+	if q == "" {
+		return nil, atColumn("question", fmt.Errorf("This row has no question and will be skipped: %s", row))
+	}
 
-	if q == "" || ref == "" {
-		return nil, fmt.Errorf("This row has empty columns and will be skipped: %s", row)
+	if _, ok := fieldTypeProperties[questionType]; !ok && !screenTypes[questionType] {
+		return nil, atColumn("type", fmt.Errorf("Unknown question type %s for question %s", questionType, ref))
 	}
 
-	choices := []*trans.FieldChoice{}
+	choices := []*FieldChoice{}
 	var title string
 
-	options := get(row, 3)
-	description := get(row, 4)
+	options := cols.Get(row, "options")
+	description := cols.Get(row, "description")
+
+	// BuildForm puts the choices of known lists in the options
+	if list, ok := listName(options); ok {
+		return nil, atColumn("options", fmt.Errorf("Could not find choice list %s for question %s", list, ref))
+	}
+
+	choiceRefs, err := parseChoiceRefs(cols.Get(row, "choice_refs"))
+	if err != nil {
+		return nil, atColumn("choice_refs", fmt.Errorf("Could not build question %s: %w", ref, err))
+	}
 
 	title = q
 
-	if questionType == "multiple_choice" {
+	if choiceTypes[questionType] {
 		if options == "" {
-			return nil, fmt.Errorf("multiple_choice question without options! Skipping. Row: %s", row)
+			return nil, atColumn("options", fmt.Errorf("%s question without options! Skipping. Row: %s", questionType, row))
 		}
 
 		answers, err := trans.ExtractLabels(options)
 
 		if err != nil {
-			return nil, err
+			return nil, atColumn("options", err)
 		}
 
 		if len(answers) == 0 {
@@ -101,61 +134,217 @@ func BuildField(row []string) (interface{}, error) {
 			title = fmt.Sprintf("%s\n\n%s", strings.TrimSpace(q), strings.TrimSpace(options))
 			for _, answer := range answers {
 				label := answer.Response
-				choices = append(choices, &trans.FieldChoice{Label: label})
+				choices = append(choices, &FieldChoice{Label: label})
 			}
 		}
+
+		err = setChoiceRefs(choices, choiceRefs)
+		if err != nil {
+			return nil, atColumn("choice_refs", fmt.Errorf("Could not build question %s: %w", ref, err))
+		}
 	}
 
-	if questionType == "thankyou_screen" {
-		f := &ThankyouScreen{
+	if questionType == "picture_choice" {
+		if options == "" {
+			return nil, atColumn("options", fmt.Errorf("picture_choice question without options! Skipping. Row: %s", row))
+		}
+
+		choices, err = extractPictureChoices(options)
+		if err != nil {
+			return nil, atColumn("options", err)
+		}
+
+		err = setChoiceRefs(choices, choiceRefs)
+		if err != nil {
+			return nil, atColumn("choice_refs", fmt.Errorf("Could not build question %s: %w", ref, err))
+		}
+	}
+
+	if questionType == "matrix" && options == "" {
+		return nil, atColumn("options", fmt.Errorf("matrix question without a scale in the options! Skipping. Row: %s", row))
+	}
+
+	if questionType == "welcome_screen" {
+		buttonText := cols.Get(row, "button_text")
+		f := &WelcomeScreen{
 			Ref:   ref,
 			Title: title,
+			Properties: &WelcomeScreenProperties{
+				Description: description,
+				ShowButton:  buttonText != "",
+				ButtonText:  buttonText,
+			},
+			Attachment: imageAttachment(cols.Get(row, "attachment")),
 		}
 		return f, nil
 	}
 
+	if questionType == "thankyou_screen" {
+		f := &ThankyouScreen{
+			Ref:        ref,
+			Title:      title,
+			Attachment: imageAttachment(cols.Get(row, "attachment")),
+		}
+
+		props, err := buildThankyouProperties(cols, row)
+		if err != nil {
+			return nil, fmt.Errorf("Could not build thankyou screen %s: %w", ref, err)
+		}
+		f.Properties = props
+
+		return f, nil
+	}
+
 	if questionType == "hidden" {
 		f := HiddenVariable(ref)
 		return f, nil
 	}
 
-	f := &trans.Field{
+	f := &Field{
 		Type:  questionType,
 		Title: title,
 		Ref:   ref,
-		Properties: &trans.FieldProperties{
+		Properties: &FieldProperties{
 			Choices:     choices,
 			Description: description,
 		},
 	}
+
+	err = ParseProperties(f, cols.Get(row, "properties"))
+	if err != nil {
+		return nil, atColumn("properties", fmt.Errorf("Could not build question %s: %w", ref, err))
+	}
+
+	err = ParseValidations(f, cols.Get(row, "required"), cols.Get(row, "validations"))
+	if err != nil {
+		column := "validations"
+		if cols.Get(row, "validations") == "" {
+			column = "required"
+		}
+		return nil, atColumn(column, fmt.Errorf("Could not build question %s: %w", ref, err))
+	}
+
+	f.Attachment, f.Layout, err = ParseAttachment(cols.Get(row, "attachment"), cols.Get(row, "attachment_properties"))
+	if err != nil {
+		return nil, atColumn("attachment", fmt.Errorf("Could not build question %s: %w", ref, err))
+	}
+
+	err = ParseScores(f, cols.Get(row, "score"))
+	if err != nil {
+		return nil, atColumn("score", fmt.Errorf("Could not build question %s: %w", ref, err))
+	}
+
 	return f, nil
 }
 
-func BuildForm(title string, records [][]string) (*Form, error) {
-	fields := []*trans.Field{}
+// BuildForm builds the form of the rows of a survey sheet, after its
+// header. Rows with a problem are left out of the form and reported,
+// numbered as in the sheet.
+func BuildForm(title string, cols Columns, lists ChoiceLists, records [][]string) (*Form, ErrorReport, error) {
+	report := ErrorReport{}
+	fields := []*Field{}
+	welcomeScreens := []*WelcomeScreen{}
 	thankyouScreens := []*ThankyouScreen{}
 	hiddenVariables := []HiddenVariable{}
 
-	for _, record := range records {
-		f, err := BuildField(record)
+	// the shared scale of each matrix and its choice refs, by ref
+	scales := map[string]string{}
+	scaleRefs := map[string]string{}
+
+	// matrix rows and choice lists fill in options, even
+	// in sheets without an options column
+	cols = cols.with("options").with("choice_refs")
+
+	// the row of each question and screen, by ref
+	refRows := map[string]int{}
+
+	// the row of each thankyou screen, for its errors
+	screenRows := map[*ThankyouScreen]int{}
+
+	for i, record := range records {
+		row := i + 2
+
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		if cols.Get(record, "type") == "matrix" {
+			scales[cols.Get(record, "ref")] = cols.Get(record, "options")
+			scaleRefs[cols.Get(record, "ref")] = cols.Get(record, "choice_refs")
+		}
+
+		// rows of a matrix take their options from the matrix
+		group := cols.Get(record, "group")
+		if scale, ok := scales[group]; ok && cols.Get(record, "options") == "" {
+			record = cols.Set(record, "options", scale)
+			record = cols.Set(record, "choice_refs", scaleRefs[group])
+		}
+
+		// options from the Choices sheet
+		if list, ok := listName(cols.Get(record, "options")); ok {
+			if options, refs, ok := lists.options(list); ok {
+				record = cols.Set(record, "options", options)
+				record = cols.Set(record, "choice_refs", refs)
+			}
+		}
+
+		f, err := BuildField(cols, record)
 		if err != nil {
-			fmt.Println(err)
-			// hrm...
+			report = append(report, newRowError(cols, row, err))
 			continue
 		}
 
+		if _, hidden := f.(HiddenVariable); !hidden {
+			ref := cols.Get(record, "ref")
+			if r, ok := refRows[ref]; ok {
+				report = append(report, newRowError(cols, row, atColumn("ref", fmt.Errorf("The ref %s is already used in row %d", ref, r))))
+				continue
+			}
+			refRows[ref] = row
+		}
+
 		switch f.(type) {
-		case *trans.Field:
-			fields = append(fields, f.(*trans.Field))
+		case *Field:
+			group := cols.Get(record, "group")
+			if group == "" {
+				fields = append(fields, f.(*Field))
+			} else if err := addToGroup(fields, group, f.(*Field)); err != nil {
+				report = append(report, newRowError(cols, row, atColumn("group", err)))
+			}
+		case *WelcomeScreen:
+			welcomeScreens = append(welcomeScreens, f.(*WelcomeScreen))
 		case *ThankyouScreen:
 			thankyouScreens = append(thankyouScreens, f.(*ThankyouScreen))
+			screenRows[f.(*ThankyouScreen)] = row
 		case HiddenVariable:
 			hiddenVariables = append(hiddenVariables, f.(HiddenVariable))
 		}
 
 	}
 
-	return &Form{Title: title, Fields: fields, ThankYouScreens: thankyouScreens, Hidden: hiddenVariables}, nil
+	// hidden fields can be declared anywhere in the sheet
+	checkedScreens := []*ThankyouScreen{}
+	for _, screen := range thankyouScreens {
+		if err := checkRedirectHidden(screen, hiddenVariables); err != nil {
+			report = append(report, newRowError(cols, screenRows[screen], atColumn("redirect_url", err)))
+			continue
+		}
+		checkedScreens = append(checkedScreens, screen)
+	}
+	thankyouScreens = checkedScreens
+
+	form := &Form{Title: title, Fields: fields, WelcomeScreens: welcomeScreens, ThankYouScreens: thankyouScreens, Hidden: hiddenVariables}
+
+	// a typo in a recall would only show as broken text to respondents
+	if problems := recallProblems(form); len(problems) > 0 {
+		recalls := ErrorReport{}
+		for _, p := range problems {
+			recalls = append(recalls, newRowError(cols, refRows[p.ref], atColumn(p.column, errors.New(p.message))))
+		}
+		return nil, report, &ReportError{recalls}
+	}
+
+	return form, report, nil
 }
 
 type ErrorDetail struct {
@@ -179,9 +368,95 @@ func (e *TypeformError) Empty() bool {
 	return e.Code == ""
 }
 
+type ThankyouScreenProperties struct {
+	ShowButton  bool   `json:"show_button"`
+	ShareIcons  *bool  `json:"share_icons,omitempty"`
+	ButtonMode  string `json:"button_mode,omitempty"`
+	ButtonText  string `json:"button_text,omitempty"`
+	RedirectUrl string `json:"redirect_url,omitempty"`
+}
+
 type ThankyouScreen struct {
-	Ref   string `json:"ref"`
-	Title string `json:"title"`
+	Ref        string                    `json:"ref"`
+	Title      string                    `json:"title"`
+	Properties *ThankyouScreenProperties `json:"properties,omitempty"`
+	Attachment *Attachment               `json:"attachment,omitempty"`
+}
+
+// buildThankyouProperties reads the button, redirect and share icon
+// columns of a thankyou_screen row. If none are set, the screen
+// keeps the Typeform defaults.
+func buildThankyouProperties(cols Columns, row []string) (*ThankyouScreenProperties, error) {
+	buttonText := cols.Get(row, "button_text")
+	redirect := strings.TrimSpace(cols.Get(row, "redirect_url"))
+	shareIcons := cols.Get(row, "share_icons")
+
+	if buttonText == "" && redirect == "" && shareIcons == "" {
+		return nil, nil
+	}
+
+	props := &ThankyouScreenProperties{
+		ShowButton: buttonText != "" || redirect != "",
+		ButtonText: buttonText,
+		ButtonMode: "reload",
+	}
+
+	if redirect != "" {
+		u, err := url.Parse(redirect)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, atColumn("redirect_url", fmt.Errorf("The redirect_url should be an http(s) URL, got: %s", redirect))
+		}
+		props.ButtonMode = "redirect"
+		props.RedirectUrl = redirect
+	}
+
+	if shareIcons != "" {
+		share, err := parseYesNo("share_icons", shareIcons)
+		if err != nil {
+			return nil, atColumn("share_icons", err)
+		}
+		props.ShareIcons = &share
+	}
+
+	return props, nil
+}
+
+var hiddenPlaceholder = regexp.MustCompile(`{{\s*hidden:([^}\s]+)\s*}}`)
+
+// checkRedirectHidden makes sure the hidden fields interpolated
+// into the redirect url of a thankyou screen exist in the form.
+func checkRedirectHidden(screen *ThankyouScreen, hidden []HiddenVariable) error {
+	if screen.Properties == nil {
+		return nil
+	}
+
+	for _, match := range hiddenPlaceholder.FindAllStringSubmatch(screen.Properties.RedirectUrl, -1) {
+		found := false
+		for _, h := range hidden {
+			if string(h) == match[1] {
+				found = true
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("Thankyou screen %s redirects with hidden field %s, which is not in the form", screen.Ref, match[1])
+		}
+	}
+
+	return nil
+}
+
+type WelcomeScreenProperties struct {
+	Description string `json:"description,omitempty"`
+	ShowButton  bool   `json:"show_button,omitempty"`
+	ButtonText  string `json:"button_text,omitempty"`
+}
+
+type WelcomeScreen struct {
+	Ref        string                   `json:"ref"`
+	Title      string                   `json:"title"`
+	Properties *WelcomeScreenProperties `json:"properties,omitempty"`
+	Attachment *Attachment              `json:"attachment,omitempty"`
 }
 
 type HiddenVariable string
@@ -195,19 +470,26 @@ type Workspace struct {
 
 type Form struct {
 	// add workspace and other things
-	ID              string            `json:"id,omitempty"`
-	Workspace       Workspace         `json:"workspace,omitempty"`
-	Title           string            `json:"title"`
-	Fields          []*trans.Field    `json:"fields"`
-	ThankYouScreens []*ThankyouScreen `json:"thankyou_screens,omitempty"`
-	Logic           json.RawMessage   `json:"logic,omitempty"`
-	Hidden          []HiddenVariable  `json:"hidden,omitempty"`
+	ID              string             `json:"id,omitempty"`
+	Workspace       Workspace          `json:"workspace,omitempty"`
+	Title           string             `json:"title"`
+	Fields          []*Field           `json:"fields"`
+	WelcomeScreens  []*WelcomeScreen   `json:"welcome_screens,omitempty"`
+	ThankYouScreens []*ThankyouScreen  `json:"thankyou_screens,omitempty"`
+	Logic           json.RawMessage    `json:"logic,omitempty"`
+	Variables       map[string]float64 `json:"variables,omitempty"`
+	Settings        *FormSettings      `json:"settings,omitempty"`
+	Theme           *FormTheme         `json:"theme,omitempty"`
+	Hidden          []HiddenVariable   `json:"hidden,omitempty"`
 }
 
 type TypeformUploader struct {
 	BaseUrl       string `env:"TYPEFORM_BASE_URL,required"`
 	TypeformToken string `env:"TYPEFORM_TOKEN,required"`
 	api           *sling.Sling
+
+	// Typeform image hrefs by file name, see UploadImage
+	images map[string]string
 }
 
 func (t *TypeformUploader) LoadEnv() {
@@ -310,9 +592,14 @@ type Messages map[string]string
 func ParseMessages(records [][]string) Messages {
 	messages := Messages{}
 
+	if len(records) == 0 {
+		return messages
+	}
+
+	// rows of messages not written yet can be short
 	for _, r := range records[1:] {
-		k := r[0]
-		v := r[1]
+		k := get(r, 0)
+		v := get(r, 1)
 
 		if k == "" || v == "" {
 			fmt.Printf("skipping row: %s", r)
@@ -391,6 +678,16 @@ func (t *TypeformUploader) CreateForm(conf *FormConf) error {
 		return err
 	}
 
+	err = t.UploadImages(conf)
+	if err != nil {
+		return err
+	}
+
+	err = t.ApplyTheme(conf.Form)
+	if err != nil {
+		return err
+	}
+
 	err, loc := sendForm(api, conf.Form, "POST")
 	if err != nil {
 		return err
@@ -420,16 +717,63 @@ func (t *TypeformUploader) UpdateForm(conf *FormConf, keepLogic bool) error {
 	// Set the ID and hidden fields
 	conf.Form.ID = form.ID
 
+	// Keep welcome screens made in Typeform if the sheet has none
+	if len(conf.Form.WelcomeScreens) == 0 {
+		conf.Form.WelcomeScreens = form.WelcomeScreens
+	}
+
+	// Keep images and videos added in Typeform
+	CopyAttachments(form, conf.Form)
+
+	// Keep the settings in Typeform that the sheet doesn't set,
+	// as PUT would reset them
+	conf.Form.Settings, err = mergeSettings(form.Settings, conf.Form.Settings)
+	if err != nil {
+		return err
+	}
+
 	if keepLogic {
 		// Thinking it's better to have all hidden in excel
 		// conf.Form.Hidden = form.Hidden
 
 		conf.Form.Logic = form.Logic
 
+		// the logic in Typeform might use its variables
+		if len(conf.Form.Variables) == 0 {
+			conf.Form.Variables = form.Variables
+		}
+
 		// Add any refs available in the source
 		conf.Form.Fields, _ = CopyChoiceRefs(form, conf.Form, true)
 	}
 
+	// Logic from the Logic sheet replaces the logic in Typeform. It is
+	// built again as the choice refs might have changed. Translations
+	// have no Logic sheet, they keep the logic of their base form. Rows
+	// with a problem were reported when the form was built.
+	logic, err := BuildLogic(conf.Form, conf.Sheet, conf.LogicData)
+	var re *ReportError
+	if err != nil && !errors.As(err, &re) {
+		return err
+	}
+	if logic != nil {
+		conf.Form.Logic = logic
+	}
+
+	err = t.UploadImages(conf)
+	if err != nil {
+		return err
+	}
+
+	// Keep the theme in Typeform if the sheet names none
+	err = t.ApplyTheme(conf.Form)
+	if err != nil {
+		return err
+	}
+	if conf.Form.Theme == nil {
+		conf.Form.Theme = form.Theme
+	}
+
 	err, _ = sendForm(api, conf.Form, "PUT")
 	return err
 }
@@ -464,59 +808,121 @@ func (t *TypeformUploader) GetByName(workspace, name string) (*Form, error) {
 	return nil, fmt.Errorf("Could not find form with name: %s", name)
 }
 
-func (t *TypeformUploader) BaseForms(workspace, basePath string) (map[string]*FormConf, error) {
+func (t *TypeformUploader) BaseForms(workspace, basePath string) (map[string]*FormConf, ErrorReport, error) {
 	return NewSurveyFile(workspace, basePath).InitialForms()
 }
 
-func (t *TypeformUploader) Translations(workspace, basePath, translationPath string) (map[string]*FormConf, error) {
+// Translations builds the translated forms. Their language is the
+// language in the Settings sheet of the translation, if any, or else
+// the language given here.
+func (t *TypeformUploader) Translations(workspace, basePath, translationPath, language string) (map[string]*FormConf, ErrorReport, error) {
+	return t.TranslateSurvey(NewSurveyFile(workspace, basePath), NewSurveyFile(workspace, translationPath), language)
+}
+
+// TranslateSurvey builds the translated forms of the base survey, which
+// must already be in Typeform, see Translations. The report lists the
+// rows left out of the translated forms.
+func (t *TypeformUploader) TranslateSurvey(base, translation *SurveyFile, language string) (map[string]*FormConf, ErrorReport, error) {
 
-	bases, err := NewSurveyFile(workspace, basePath).InitialForms()
+	// the base forms are only needed for their names
+	bases, _, err := base.InitialForms()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	translations, err := NewSurveyFile(workspace, translationPath).InitialForms()
+	translation.Translation = true
+	translations, report, err := translation.InitialForms()
 	if err != nil {
-		return nil, err
+		return nil, report, err
 	}
 
 	for sheet, baseConf := range bases {
-		actualForm, err := t.GetByName(workspace, baseConf.Name)
+		actualForm, err := t.GetByName(base.Workspace, baseConf.Name)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		translationConf, ok := translations[sheet]
 
 		if !ok {
-			return nil, fmt.Errorf("Could not find translation for form: %s", baseConf.Name)
+			return nil, nil, fmt.Errorf("Could not find translation for form: %s", baseConf.Name)
+		}
+
+		lang := language
+		if s := translationConf.Form.Settings; s != nil && s.Language != "" {
+			lang = s.Language
 		}
 
 		newForm, err := TranslateForm(actualForm, translationConf.Form)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+
+		if lang == "" {
+			fmt.Printf("No language for the translation of %s, it keeps the language of the base form\n", baseConf.Name)
+		} else {
+			newForm.Settings, err = mergeSettings(newForm.Settings, &FormSettings{Language: lang})
+			if err != nil {
+				return nil, nil, err
+			}
 		}
 
 		translationConf.Form = newForm
 	}
 
-	return translations, nil
+	return translations, report, nil
 }
 
 type FormConf struct {
 	Name         string
 	Form         *Form
 	MessagesData [][]string
+
+	// directory that local images are relative to
+	Dir string
+
+	// the sheet the form was built from and the Logic sheet, if any
+	Sheet     string
+	LogicData [][]string
+
+	// the rows of the sheet left out of the form
+	Errors ErrorReport
 }
 
-func NewFormConf(workspace, name string, formData [][]string, messagesData [][]string) (*FormConf, error) {
-	form, err := BuildForm(name, formData[1:])
+func NewFormConf(workspace, name string, formData [][]string, messagesData [][]string, lists ChoiceLists) (*FormConf, error) {
+	if len(formData) == 0 {
+		return nil, fmt.Errorf("Form %s has no header row", name)
+	}
+
+	cols, err := ParseColumns(formData[0])
+	if err != nil {
+		return nil, err
+	}
+
+	form, report, err := BuildForm(name, cols, lists, formData[1:])
 	if err != nil {
 		return nil, err
 	}
 	form.Workspace = Workspace{fmt.Sprintf("https://api.typeform.com/workspaces/%s", workspace)}
 
-	conf := &FormConf{name, form, messagesData}
+	// messages with a bad recall are left out, like rows of the form
+	if messages := messagesRecallReport(form, messagesData); len(messages) > 0 {
+		bad := map[int]bool{}
+		for _, e := range messages {
+			bad[e.Row] = true
+		}
+
+		kept := [][]string{}
+		for i, r := range messagesData {
+			if !bad[i+1] {
+				kept = append(kept, r)
+			}
+		}
+		messagesData = kept
+		report = append(report, messages...)
+	}
+
+	conf := &FormConf{Name: name, Form: form, MessagesData: messagesData, Errors: report}
 	return conf, nil
 }
 
@@ -574,22 +980,296 @@ func runCreate(uploader TypeformUploader, formConfs map[string]*FormConf, sheet
 	}
 }
 
-func runBaseCreate(uploader TypeformUploader, workspace, basePath, sheet string, update bool) {
-	formConfs, err := uploader.BaseForms(workspace, basePath)
-	handle(err)
+// checkReport keeps the rows left out of the forms of the sheet, or of
+// every form without a sheet. With strict, if there are any, it returns
+// an error, so that nothing is uploaded.
+func checkReport(report ErrorReport, sheet string, strict bool) (ErrorReport, error) {
+	if sheet != "" {
+		res := ErrorReport{}
+		for _, e := range report {
+			if e.Sheet == sheet {
+				res = append(res, e)
+			}
+		}
+		report = res
+	}
+
+	if strict && len(report) > 0 {
+		return report, fmt.Errorf("Nothing was uploaded, as rows were left out of the forms and --strict is on")
+	}
+	return report, nil
+}
+
+// handleReport writes annotated copies of the workbooks with rows in the
+// report. If the forms could not be built, it prints it and returns the
+// error.
+func handleReport(report ErrorReport, err error) error {
+	writeErrorWorkbooks(report)
+
+	if err != nil {
+		report.Print(os.Stdout)
+	}
+	return err
+}
+
+func runBaseCreate(uploader TypeformUploader, workspace, basePath, theme, sheet string, update, strict bool) error {
+	formConfs, report, err := uploader.BaseForms(workspace, basePath)
+	if err := handleReport(report, err); err != nil {
+		return err
+	}
+
+	report, err = checkReport(report, sheet, strict)
+	if err != nil {
+		report.Print(os.Stdout)
+		return err
+	}
+	useTheme(formConfs, theme)
 
 	runCreate(uploader, formConfs, sheet, update, true)
+	report.Print(os.Stdout)
+	return nil
 }
 
-func runTranslations(uploader TypeformUploader, workspace, basePath, translations, sheet string, update bool) {
-	formConfs, err := uploader.Translations(workspace, basePath, translations)
-	handle(err)
+func runTranslations(uploader TypeformUploader, workspace, basePath, translations, language, theme, sheet string, update, strict bool) error {
+	formConfs, report, err := uploader.Translations(workspace, basePath, translations, language)
+	if err := handleReport(report, err); err != nil {
+		return err
+	}
+
+	report, err = checkReport(report, sheet, strict)
+	if err != nil {
+		report.Print(os.Stdout)
+		return err
+	}
+	useTheme(formConfs, theme)
 
 	runCreate(uploader, formConfs, sheet, update, false)
+	report.Print(os.Stdout)
+	return nil
+}
+
+// runProject creates or updates the base forms of the project and then
+// the forms of each of its translations.
+func runProject(uploader TypeformUploader, path, sheet string, update, strict bool) error {
+	project, err := LoadProject(path)
+	if err != nil {
+		return err
+	}
+
+	formConfs, report, err := project.Base().InitialForms()
+	if err := handleReport(report, err); err != nil {
+		return err
+	}
+
+	// translations are built after their base forms are uploaded,
+	// so strict checks their rows first
+	if strict {
+		for _, f := range project.TranslationFiles {
+			_, r, err := project.Translation(f).InitialForms()
+			if err := handleReport(r, err); err != nil {
+				return err
+			}
+			report = append(report, r...)
+		}
+	}
+	if r, err := checkReport(report, sheet, strict); err != nil {
+		r.Print(os.Stdout)
+		return err
+	}
+
+	if project.BaseFile.Lang != "" {
+		lang, err := languageFromName(project.BaseFile.Lang)
+		if err != nil {
+			return err
+		}
+		useLanguage(formConfs, lang)
+	}
+	useTheme(formConfs, project.Theme)
+
+	runCreate(uploader, formConfs, sheet, update, true)
+
+	for _, f := range project.TranslationFiles {
+		lang, err := languageFromName(f.Lang)
+		if err != nil {
+			return err
+		}
+
+		formConfs, r, err := uploader.TranslateSurvey(project.Base(), project.Translation(f), lang)
+		if err := handleReport(r, err); err != nil {
+			return err
+		}
+		if !strict {
+			report = append(report, r...)
+		}
+
+		useTheme(formConfs, project.Theme)
+		runCreate(uploader, formConfs, sheet, update, false)
+	}
+
+	report, _ = checkReport(report, sheet, false)
+	report.Print(os.Stdout)
+	return nil
 }
 
-func runDirect(uploader TypeformUploader, workspace, basePath string) {
+// runXLSForm creates or updates the form of an XLSForm workbook, after
+// listing everything that could not be imported.
+func runXLSForm(uploader TypeformUploader, workspace, path, theme string, update, strict bool) error {
+	conf, problems, err := ImportXLSForm(workspace, path)
+	if err != nil {
+		return err
+	}
 
+	if len(problems) > 0 {
+		fmt.Printf("Could not import everything from %s:\n", path)
+		for _, p := range problems {
+			fmt.Println("  " + p)
+		}
+
+		if strict {
+			return fmt.Errorf("Nothing was uploaded, as --strict is on")
+		}
+	}
+
+	formConfs := map[string]*FormConf{conf.Sheet: conf}
+	useTheme(formConfs, theme)
+
+	runCreate(uploader, formConfs, "", update, false)
+	return nil
+}
+
+func saveXLSForm(path string, form *Form, translations map[string]*Form) error {
+	problems, err := ExportXLSForm(path, form, translations)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range problems {
+		fmt.Printf("%s: %s\n", path, p)
+	}
+	fmt.Printf("Wrote %s\n", path)
+	return nil
+}
+
+// runExportXLSForm writes XLSForm workbooks: the form with the id to the
+// path, or else every form of the project or base file, with the
+// translations of the project, to the path as a directory.
+func runExportXLSForm(uploader TypeformUploader, formId, config, workspace, basePath, path string) error {
+	if formId != "" {
+		form, err := uploader.GetForm(formId)
+		if err != nil {
+			return err
+		}
+		return saveXLSForm(path, form, nil)
+	}
+
+	base := NewSurveyFile(workspace, basePath)
+	var project *Project
+
+	if config != "" {
+		var err error
+		project, err = LoadProject(config)
+		if err != nil {
+			return err
+		}
+		base = project.Base()
+	}
+
+	formConfs, report, err := base.InitialForms()
+	if err := handleReport(report, err); err != nil {
+		return err
+	}
+
+	if project != nil && project.BaseFile.Lang != "" {
+		lang, err := languageFromName(project.BaseFile.Lang)
+		if err != nil {
+			return err
+		}
+		useLanguage(formConfs, lang)
+	}
+
+	translations := map[string]map[string]*FormConf{}
+	if project != nil {
+		for _, f := range project.TranslationFiles {
+			lang, err := languageFromName(f.Lang)
+			if err != nil {
+				return err
+			}
+
+			confs, r, err := project.Translation(f).InitialForms()
+			if err := handleReport(r, err); err != nil {
+				return err
+			}
+			translations[lang] = confs
+			report = append(report, r...)
+		}
+	}
+
+	// translations take the choice refs and logic of their base
+	// forms, like when they are uploaded
+	for lang, confs := range translations {
+		for sheet, t := range confs {
+			c, ok := formConfs[sheet]
+			if !ok {
+				continue
+			}
+
+			form, err := TranslateForm(c.Form, t.Form)
+			if err != nil {
+				return fmt.Errorf("Could not translate %s to %s: %w", c.Name, lang, err)
+			}
+			t.Form = form
+		}
+	}
+
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+
+	for sheet, c := range formConfs {
+		forms := map[string]*Form{}
+		for lang, confs := range translations {
+			if t, ok := confs[sheet]; ok {
+				forms[lang] = t.Form
+			}
+		}
+
+		if err := saveXLSForm(filepath.Join(path, sheet+".xlsx"), c.Form, forms); err != nil {
+			return err
+		}
+	}
+
+	report.Print(os.Stdout)
+	return nil
+}
+
+func runTheme(uploader TypeformUploader, path string) error {
+	theme, err := uploader.SaveTheme(path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Saved theme %s with id %s\n", theme.Name, theme.ID)
+	return nil
+}
+
+// runDirect creates or updates the forms written as JSON or YAML
+// files, see LoadDefinitions.
+func runDirect(uploader TypeformUploader, workspace, basePath, theme, sheet string, update, strict bool) error {
+	formConfs, report, err := LoadDefinitions(workspace, basePath)
+	if err != nil {
+		return err
+	}
+
+	report, err = checkReport(report, "", strict)
+	if err != nil {
+		report.Print(os.Stdout)
+		return err
+	}
+	useTheme(formConfs, theme)
+
+	runCreate(uploader, formConfs, sheet, update, false)
+	report.Print(os.Stdout)
+	return nil
 }
 
 func runReverse(uploader TypeformUploader, formId, path string) {
@@ -625,42 +1305,106 @@ func runReverse(uploader TypeformUploader, formId, path string) {
 
 }
 
+// options are the command line flags
+type options struct {
+	workspace       string
+	basePath        string
+	translationPath string
+	language        string
+	update          bool
+	strict          bool
+	sheet           string
+	direct          bool
+	reverse         bool
+	formId          string
+	path            string
+	theme           string
+	themeFile       string
+	config          string
+	xlsform         string
+	xlsformOut      string
+}
+
+// run does what the flags ask for. Exports come before uploads, as
+// they take the same --config, --base and --form-id flags.
+func run(uploader TypeformUploader, o options) error {
+	if o.direct {
+		return runDirect(uploader, o.workspace, o.basePath, o.theme, o.sheet, o.update, o.strict)
+	}
+
+	if o.reverse {
+		runReverse(uploader, o.formId, o.path)
+		return nil
+	}
+
+	if o.themeFile != "" {
+		return runTheme(uploader, o.themeFile)
+	}
+
+	if o.xlsformOut != "" {
+		return runExportXLSForm(uploader, o.formId, o.config, o.workspace, o.basePath, o.xlsformOut)
+	}
+
+	if o.config != "" {
+		return runProject(uploader, o.config, o.sheet, o.update, o.strict)
+	}
+
+	if o.xlsform != "" {
+		return runXLSForm(uploader, o.workspace, o.xlsform, o.theme, o.update, o.strict)
+	}
+
+	if o.translationPath == "" {
+		return runBaseCreate(uploader, o.workspace, o.basePath, o.theme, o.sheet, o.update, o.strict)
+	}
+
+	lang := ""
+	if o.language != "" {
+		var err error
+		lang, err = parseLanguage(o.language)
+		if err != nil {
+			return err
+		}
+	}
+	return runTranslations(uploader, o.workspace, o.basePath, o.translationPath, lang, o.theme, o.sheet, o.update, o.strict)
+}
+
 func main() {
-	workspace := flag.String("workspace", "", "Typeform workspace id")
+	o := options{}
+
+	flag.StringVar(&o.workspace, "workspace", "", "Typeform workspace id")
+
+	flag.StringVar(&o.basePath, "base", "", "path to base file")
+	flag.StringVar(&o.translationPath, "translation", "", "path to translation file")
+	flag.StringVar(&o.language, "language", "", "language code of the translation, such as es")
 
-	basePath := flag.String("base", "", "path to base file")
-	translationPath := flag.String("translation", "", "path to translation file")
+	flag.BoolVar(&o.update, "update", false, "if you want to update. Not create. Just update.")
 
-	update := flag.Bool("update", false, "if you want to update. Not create. Just update.")
+	flag.BoolVar(&o.strict, "strict", false, "to upload nothing if any row would be left out of its form")
 
-	sheet := flag.String("sheet", "", "sheet to load individual sheet")
+	flag.StringVar(&o.sheet, "sheet", "", "sheet to load individual sheet")
 
-	direct := flag.Bool("direct", false, "to run direct from a file")
+	flag.BoolVar(&o.direct, "direct", false, "to run direct from a JSON or YAML form definition, or a directory of them, given with --base")
 
-	reverse := flag.Bool("reverse", false, "to download a file from a typeform")
+	flag.BoolVar(&o.reverse, "reverse", false, "to download a file from a typeform")
 
-	formId := flag.String("form-id", "", "form id for downloading")
+	flag.StringVar(&o.formId, "form-id", "", "form id for downloading")
 
-	path := flag.String("path", "", "path for downloading")
+	flag.StringVar(&o.path, "path", "", "path for downloading")
+
+	flag.StringVar(&o.theme, "theme", "", "id or name of the theme of the forms")
+
+	flag.StringVar(&o.themeFile, "theme-file", "", "path to a JSON or YAML theme to create or update")
+
+	flag.StringVar(&o.config, "config", "", "path to a project file with the base file and its translations")
+
+	flag.StringVar(&o.xlsform, "xlsform", "", "path to an XLSForm workbook to import")
+
+	flag.StringVar(&o.xlsformOut, "xlsform-out", "", "path to write XLSForm workbooks to")
 
 	flag.Parse()
 
 	uploader := TypeformUploader{}
 	uploader.LoadEnv()
 
-	if *direct {
-		runDirect(uploader, *workspace, *basePath)
-		return
-	}
-
-	if *reverse {
-		runReverse(uploader, *formId, *path)
-		return
-	}
-
-	if *translationPath == "" {
-		runBaseCreate(uploader, *workspace, *basePath, *sheet, *update)
-	} else {
-		runTranslations(uploader, *workspace, *basePath, *translationPath, *sheet, *update)
-	}
+	handle(run(uploader, o))
 }
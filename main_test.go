@@ -1,11 +1,13 @@
 package main
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -77,7 +79,7 @@ func TestCreateForm_CreatesAndUpdatesMessages(t *testing.T) {
 		{"var2", "message2"},
 	}
 
-	conf, _ := NewFormConf("workspace", "form name", formData, messageData)
+	conf, _ := NewFormConf("workspace", "form name", formData, messageData, nil)
 	err := uploader.CreateForm(conf)
 	assert.Nil(t, err)
 	assert.Equal(t, 3, call)
@@ -110,7 +112,7 @@ func TestCreateForm_FailsIfFormWithSameNameExists(t *testing.T) {
 		{"var1", "message1"},
 	}
 
-	conf, _ := NewFormConf("workspace", "form name", formData, messageData)
+	conf, _ := NewFormConf("workspace", "form name", formData, messageData, nil)
 	err := uploader.CreateForm(conf)
 	assert.Contains(t, err.Error(), "form name")
 	assert.Equal(t, 1, call)
@@ -153,18 +155,184 @@ func TestCreateForm_ReturnsApiErrors(t *testing.T) {
 		{"var1", "message1"},
 	}
 
-	conf, _ := NewFormConf("workspace", "form name", formData, messageData)
+	conf, _ := NewFormConf("workspace", "form name", formData, messageData, nil)
 	err := uploader.CreateForm(conf)
 	e := err.(*TypeformError)
 
 	assert.Equal(t, "SOME_CODE", e.Code)
 }
 
+func TestUpdateForm_KeepsExistingWelcomeScreensIfSheetHasNone(t *testing.T) {
+	call := 0
+
+	ts, _ := testServer(func(w http.ResponseWriter, r *http.Request) {
+		call++
+
+		if call == 1 {
+			w.WriteHeader(200)
+			fmt.Fprintf(w, `{"items": [{"id": "foo", "title": "form name"}]}`)
+		}
+
+		if call == 2 {
+			assert.Equal(t, "/forms/foo", r.URL.Path)
+			w.WriteHeader(200)
+			fmt.Fprintf(w, `{"id": "foo", "title": "form name", "fields": [], "welcome_screens": [{"ref": "consent", "title": "Welcome"}]}`)
+		}
+
+		if call == 3 {
+			assert.Equal(t, "/forms/foo", r.URL.Path)
+			assert.Equal(t, "PUT", r.Method)
+
+			form := new(Form)
+			json.NewDecoder(r.Body).Decode(form)
+			assert.Equal(t, 1, len(form.WelcomeScreens))
+			assert.Equal(t, "consent", form.WelcomeScreens[0].Ref)
+
+			w.WriteHeader(200)
+		}
+	})
+
+	uploader := TypeformUploader{
+		BaseUrl:       ts.URL,
+		TypeformToken: "secret",
+	}
+
+	formData := [][]string{
+		{"variable", "question_type", "question"},
+		{"var1", "short_text", "hello"},
+	}
+
+	conf, _ := NewFormConf("workspace", "form name", formData, [][]string{}, nil)
+	err := uploader.UpdateForm(conf, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, call)
+}
+
+func TestUpdateForm_KeepsSettingsTheSheetDoesNotSet(t *testing.T) {
+	call := 0
+
+	ts, _ := testServer(func(w http.ResponseWriter, r *http.Request) {
+		call++
+
+		if call == 1 {
+			w.WriteHeader(200)
+			fmt.Fprintf(w, `{"items": [{"id": "foo", "title": "form name"}]}`)
+		}
+
+		if call == 2 {
+			w.WriteHeader(200)
+			fmt.Fprintf(w, `{"id": "foo", "title": "form name", "fields": [], "settings": {"language": "en", "is_public": true, "progress_bar": "proportion", "meta": {"allow_indexing": false, "image": {"href": "https://images.typeform.com/a"}}, "pro_subdomain_enabled": false, "capabilities": {"e2e_encryption": {"enabled": false, "modifiable": false}}}}`)
+		}
+
+		if call == 3 {
+			assert.Equal(t, "PUT", r.Method)
+
+			body, _ := ioutil.ReadAll(r.Body)
+			form := new(Form)
+			json.Unmarshal(body, form)
+			assert.Equal(t, "en", form.Settings.Language)
+			assert.Equal(t, true, *form.Settings.IsPublic)
+			assert.Equal(t, "percentage", form.Settings.ProgressBar)
+			assert.Equal(t, false, *form.Settings.Meta.AllowIndexing)
+			assert.Equal(t, "Quiz", form.Settings.Meta.Title)
+
+			// settings the sheets cannot set are sent back as they are
+			assert.Contains(t, string(body), `"capabilities":{"e2e_encryption":{"enabled":false,"modifiable":false}}`)
+			assert.Contains(t, string(body), `"pro_subdomain_enabled":false`)
+			assert.Contains(t, string(body), `"image":{"href":"https://images.typeform.com/a"}`)
+
+			w.WriteHeader(200)
+		}
+	})
+
+	uploader := TypeformUploader{
+		BaseUrl:       ts.URL,
+		TypeformToken: "secret",
+	}
+
+	formData := [][]string{
+		{"variable", "question_type", "question"},
+		{"var1", "short_text", "hello"},
+	}
+
+	conf, _ := NewFormConf("workspace", "form name", formData, [][]string{}, nil)
+	conf.Form.Settings = &FormSettings{ProgressBar: "percentage", Meta: &FormSettingsMeta{Title: "Quiz"}}
+
+	err := uploader.UpdateForm(conf, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, call)
+}
+
+func TestCreateForm_UploadsLocalImagesOnce(t *testing.T) {
+	dir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(dir, "cat.png"), []byte("cat"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "dog.png"), []byte("dog"), 0644)
+
+	uploaded := []imageUpload{}
+	call := 0
+
+	ts, _ := testServer(func(w http.ResponseWriter, r *http.Request) {
+		call++
+
+		switch {
+		case r.URL.Path == "/forms" && r.Method == "GET":
+			fmt.Fprintf(w, `{"items": []}`)
+
+		case r.URL.Path == "/images" && r.Method == "GET":
+			// dog was uploaded in a previous run
+			fmt.Fprintf(w, `[{"id": "dog", "src": "https://images.typeform.com/images/dog", "file_name": "%s"}]`, imageFileName([]byte("dog"), "dog.png"))
+
+		case r.URL.Path == "/images" && r.Method == "POST":
+			upload := imageUpload{}
+			json.NewDecoder(r.Body).Decode(&upload)
+			uploaded = append(uploaded, upload)
+
+			w.WriteHeader(201)
+			fmt.Fprintf(w, `{"id": "cat", "src": "https://images.typeform.com/images/cat", "file_name": "%s"}`, upload.FileName)
+
+		case r.URL.Path == "/forms" && r.Method == "POST":
+			form := new(Form)
+			json.NewDecoder(r.Body).Decode(form)
+
+			choices := form.Fields[0].Properties.Choices
+			assert.Equal(t, "https://images.typeform.com/images/cat", choices[0].Attachment.Href)
+			assert.Equal(t, "https://images.typeform.com/images/dog", choices[1].Attachment.Href)
+			assert.Equal(t, "https://images.typeform.com/images/cat", choices[2].Attachment.Href)
+
+			w.Header().Set("Location", "https://api.typeform.com/forms/foobar")
+			w.WriteHeader(201)
+
+		case r.URL.Path == "/forms/foobar/messages":
+			w.WriteHeader(204)
+		}
+	})
+
+	uploader := TypeformUploader{
+		BaseUrl:       ts.URL,
+		TypeformToken: "secret",
+	}
+
+	formData := [][]string{
+		{"variable", "question_type", "question", "answers"},
+		{"pet", "picture_choice", "Pet?", "Cat | cat.png\nDog | dog.png\nAlso cat | cat.png"},
+	}
+
+	conf, _ := NewFormConf("workspace", "form name", formData, [][]string{{"variable", "message"}}, nil)
+	conf.Dir = dir
+
+	err := uploader.CreateForm(conf)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, call)
+
+	assert.Equal(t, 1, len(uploaded))
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("cat")), uploaded[0].Image)
+}
+
 func TestUploaderTranslations_GetsTranslationsBasedOnFilesAndExistingForm(t *testing.T) {
 	call := 0
 
 	// note implicitly testing NewSurveyFile here
-	baseForms, _ := NewSurveyFile("workey", "test/Survey Translation Example.xlsx").InitialForms()
+	baseForms, _, _ := NewSurveyFile("workey", "test/Survey Translation Example.xlsx").InitialForms()
 	baseForm := baseForms["Baseline"]
 
 	ts, _ := testServer(func(w http.ResponseWriter, r *http.Request) {
@@ -197,7 +365,7 @@ func TestUploaderTranslations_GetsTranslationsBasedOnFilesAndExistingForm(t *tes
 		TypeformToken: "secret",
 	}
 
-	translations, err := uploader.Translations("workey", "test/Survey Translation Example.xlsx", "test/Survey Translation Example Spanish.xlsx")
+	translations, _, err := uploader.Translations("workey", "test/Survey Translation Example.xlsx", "test/Survey Translation Example Spanish.xlsx", "es")
 	assert.Nil(t, err)
 
 	assert.Equal(t, 2, call)
@@ -218,6 +386,87 @@ func TestUploaderTranslations_GetsTranslationsBasedOnFilesAndExistingForm(t *tes
 	assert.Equal(t, "Te parece?", translations["Baseline"].Form.Fields[2].Title)
 	assert.Equal(t, "Si", translations["Baseline"].Form.Fields[2].Properties.Choices[0].Label)
 
+	// Form is in the language of the translation
+	assert.Equal(t, "es", translations["Baseline"].Form.Settings.Language)
+
 }
 
 // You added stupid messages-only, now test that...
+
+func TestTranslations_KeepTheLogicOfTheBaseForm(t *testing.T) {
+	dir := t.TempDir()
+	logic := [][]string{
+		{"field", "choice", "jump_to"},
+		{"consent", "No", "optout"},
+	}
+	messages := [][]string{{"variable", "message"}}
+
+	basePath := filepath.Join(dir, "survey.xlsx")
+	writeWorkbook(t, basePath, []testSheet{
+		{"Baseline", [][]string{
+			{"ref", "type", "question", "options"},
+			{"consent", "multiple_choice", "Consent?", "Yes\nNo"},
+			{"name", "short_text", "Name?"},
+			{"optout", "thankyou_screen", "Bye"},
+		}},
+		{"Messages", messages},
+		{"Logic", logic},
+	})
+
+	// the Logic sheet was copied along, its choices are not translated
+	translationPath := filepath.Join(dir, "survey french.xlsx")
+	writeWorkbook(t, translationPath, []testSheet{
+		{"Baseline", [][]string{
+			{"ref", "type", "question", "options"},
+			{"consent", "multiple_choice", "D'accord ?", "Oui\nNon"},
+			{"name", "short_text", "Nom ?"},
+			{"optout", "thankyou_screen", "Au revoir"},
+		}},
+		{"Messages", messages},
+		{"Logic", logic},
+	})
+
+	baseForms, _, err := NewSurveyFile("workey", basePath).InitialForms()
+	assert.Nil(t, err)
+	baseForm := baseForms["Baseline"].Form
+
+	call := 0
+	ts, _ := testServer(func(w http.ResponseWriter, r *http.Request) {
+		call++
+
+		switch call {
+		case 1:
+			fmt.Fprintf(w, `{"items": [{"id": "foo", "title": "survey - Baseline"}]}`)
+		case 2:
+			b, _ := json.Marshal(baseForm)
+			w.Write(b)
+		case 3:
+			fmt.Fprintf(w, `{"items": [{"id": "bar", "title": "survey french - Baseline"}]}`)
+		case 4:
+			fmt.Fprintf(w, `{"id": "bar", "title": "survey french - Baseline", "fields": []}`)
+		case 5:
+			assert.Equal(t, "PUT", r.Method)
+
+			form := new(Form)
+			json.NewDecoder(r.Body).Decode(form)
+			assert.JSONEq(t, string(baseForm.Logic), string(form.Logic))
+			assert.Equal(t, "Non", form.Fields[0].Properties.Choices[1].Label)
+			assert.Equal(t, "consent_2", form.Fields[0].Properties.Choices[1].Ref)
+		}
+	})
+
+	uploader := TypeformUploader{
+		BaseUrl:       ts.URL,
+		TypeformToken: "secret",
+	}
+
+	translations, _, err := uploader.Translations("workey", basePath, translationPath, "fr")
+	assert.Nil(t, err)
+
+	conf := translations["Baseline"]
+	assert.Nil(t, conf.LogicData)
+
+	err = uploader.UpdateForm(conf, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, call)
+}
@@ -1,89 +1,99 @@
 package main
 
 import (
+	"encoding/json"
 	"github.com/stretchr/testify/assert"
-	"github.com/vlab-research/trans"
 	"testing"
 )
 
+// DefaultColumns is the historical fixed layout of survey sheets,
+// which most tests use.
+var DefaultColumns = Columns{
+	"ref":         0,
+	"type":        1,
+	"question":    2,
+	"options":     3,
+	"description": 4,
+}
+
 func TestParseCsv(t *testing.T) {
 	assert.Equal(t, true, true)
 }
 
 func TestBuildField_GetsRefAndDescription(t *testing.T) {
-	i, _ := BuildField([]string{"ref", "multiple_choice", "foo", "yes\nno", "description"})
-	f := i.(*trans.Field)
+	i, _ := BuildField(DefaultColumns, []string{"ref", "multiple_choice", "foo", "yes\nno", "description"})
+	f := i.(*Field)
 	assert.Equal(t, "ref", f.Ref)
 	assert.Equal(t, "description", f.Properties.Description)
 }
 
 func TestBuildField_ErrorsWhenMultipleChoiceHasNoAnswers(t *testing.T) {
-	_, e := BuildField([]string{"ref", "multiple_choice", "foo", "", "description"})
+	_, e := BuildField(DefaultColumns, []string{"ref", "multiple_choice", "foo", "", "description"})
 	assert.NotNil(t, e)
 }
 
 func TestBuildField_GetsTitleFromOpenQuestions(t *testing.T) {
-	i, _ := BuildField([]string{"ref", "short_text", "foo", "", ""})
-	f := i.(*trans.Field)
+	i, _ := BuildField(DefaultColumns, []string{"ref", "short_text", "foo", "", ""})
+	f := i.(*Field)
 	assert.Equal(t, "foo", f.Title)
 }
 
 func TestBuildField_GetsThankyouScreen(t *testing.T) {
-	i, _ := BuildField([]string{"ref", "thankyou_screen", "foo", "", ""})
+	i, _ := BuildField(DefaultColumns, []string{"ref", "thankyou_screen", "foo", "", ""})
 	ty := i.(*ThankyouScreen)
 	assert.Equal(t, "foo", ty.Title)
 }
 
 func TestBuildField_GetsHiddenVariable(t *testing.T) {
-	i, _ := BuildField([]string{"ref", "hidden", "foo", "", ""})
+	i, _ := BuildField(DefaultColumns, []string{"ref", "hidden", "foo", "", ""})
 	v := i.(HiddenVariable)
 	assert.Equal(t, HiddenVariable("ref"), v)
 }
 
 func TestBuildField_GetsTitleFromMultipleChoiceQuestion(t *testing.T) {
-	i, _ := BuildField([]string{"ref", "multiple_choice", "foo", "A. yes\nB. no", ""})
-	f := i.(*trans.Field)
+	i, _ := BuildField(DefaultColumns, []string{"ref", "multiple_choice", "foo", "A. yes\nB. no", ""})
+	f := i.(*Field)
 	assert.Equal(t, "foo\n\nA. yes\nB. no", f.Title)
 
-	i, _ = BuildField([]string{"ref", "multiple_choice", "foo\n", "A. yes\nB. no", ""})
-	f = i.(*trans.Field)
+	i, _ = BuildField(DefaultColumns, []string{"ref", "multiple_choice", "foo\n", "A. yes\nB. no", ""})
+	f = i.(*Field)
 	assert.Equal(t, "foo\n\nA. yes\nB. no", f.Title)
 
-	i, _ = BuildField([]string{"ref", "multiple_choice", "foo", "yes\nno", ""})
-	f = i.(*trans.Field)
+	i, _ = BuildField(DefaultColumns, []string{"ref", "multiple_choice", "foo", "yes\nno", ""})
+	f = i.(*Field)
 	assert.Equal(t, "foo", f.Title)
 }
 
 func TestBuildField_GetsChoicesFromMultipleChoiceQuestionWithLabels(t *testing.T) {
-	i, _ := BuildField([]string{"ref", "multiple_choice", "foo", "A. yes\nB. no", ""})
-	f := i.(*trans.Field)
-	assert.Equal(t, f.Properties.Choices, []*trans.FieldChoice{{"", "A", ""}, {"", "B", ""}})
+	i, _ := BuildField(DefaultColumns, []string{"ref", "multiple_choice", "foo", "A. yes\nB. no", ""})
+	f := i.(*Field)
+	assert.Equal(t, f.Properties.Choices, []*FieldChoice{{Label: "A"}, {Label: "B"}})
 }
 
 func TestBuildField_GetsChoicesFromMultipleChoiceQuestionWithoutLabels(t *testing.T) {
-	i, _ := BuildField([]string{"ref", "multiple_choice", "foo", "yes\nno", ""})
-	f := i.(*trans.Field)
-	assert.Equal(t, f.Properties.Choices, []*trans.FieldChoice{{"", "yes", ""}, {"", "no", ""}})
+	i, _ := BuildField(DefaultColumns, []string{"ref", "multiple_choice", "foo", "yes\nno", ""})
+	f := i.(*Field)
+	assert.Equal(t, f.Properties.Choices, []*FieldChoice{{Label: "yes"}, {Label: "no"}})
 
-	i, _ = BuildField([]string{"ref", "multiple_choice", "foo", "\nyes\nno", ""})
-	f = i.(*trans.Field)
-	assert.Equal(t, f.Properties.Choices, []*trans.FieldChoice{{"", "yes", ""}, {"", "no", ""}})
+	i, _ = BuildField(DefaultColumns, []string{"ref", "multiple_choice", "foo", "\nyes\nno", ""})
+	f = i.(*Field)
+	assert.Equal(t, f.Properties.Choices, []*FieldChoice{{Label: "yes"}, {Label: "no"}})
 }
 
 func TestBuildField_GetsChoicesFromMultipleChoiceQuestionSkippingLetters(t *testing.T) {
-	i, _ := BuildField([]string{"ref", "multiple_choice", "foo", "A. yes\nC. no", ""})
-	f := i.(*trans.Field)
-	assert.Equal(t, f.Properties.Choices, []*trans.FieldChoice{{"", "A", ""}, {"", "C", ""}})
+	i, _ := BuildField(DefaultColumns, []string{"ref", "multiple_choice", "foo", "A. yes\nC. no", ""})
+	f := i.(*Field)
+	assert.Equal(t, f.Properties.Choices, []*FieldChoice{{Label: "A"}, {Label: "C"}})
 }
 
 func TestBuildForm_IgnoresBlankLines(t *testing.T) {
 	records := [][]string{
 		{"", "", "", ""},
 		{"", "", "", ""},
-		{"ref", "foo", "A. yes\nC. no", ""},
+		{"ref", "short_text", "A. yes\nC. no", ""},
 	}
 
-	form, err := BuildForm("foo", records)
+	form, _, err := BuildForm("foo", DefaultColumns, nil, records)
 
 	assert.Nil(t, err)
 	assert.Equal(t, 1, len(form.Fields))
@@ -100,3 +110,470 @@ func TestParseMessages_SkipsEmpty(t *testing.T) {
 	assert.Equal(t, 1, len(m))
 	assert.Equal(t, "baz", m["foo.bar"])
 }
+
+func TestParseMessages_SkipsShortRows(t *testing.T) {
+	records := [][]string{
+		{"variable", "message"},
+		{"label.buttonHint.default"},
+		{},
+		{"foo.bar", "baz"},
+	}
+
+	m := ParseMessages(records)
+	assert.Equal(t, Messages{"foo.bar": "baz"}, m)
+
+	assert.Equal(t, Messages{}, ParseMessages(nil))
+}
+
+func TestParseColumns_ResolvesAliasesInAnyOrder(t *testing.T) {
+	cols, err := ParseColumns([]string{"Question", "notes", "Question Type", "answers", "variable"})
+	assert.Nil(t, err)
+	assert.Equal(t, Columns{"question": 0, "type": 2, "options": 3, "ref": 4}, cols)
+}
+
+func TestParseColumns_ErrorsOnUnknownHeader(t *testing.T) {
+	_, err := ParseColumns([]string{"ref", "type", "question", "foo"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "foo")
+}
+
+func TestParseColumns_ErrorsOnMissingRequiredHeader(t *testing.T) {
+	_, err := ParseColumns([]string{"ref", "question", "options"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "type")
+}
+
+func TestColumnsSet_LeavesRowWithoutTheColumn(t *testing.T) {
+	cols, _ := ParseColumns([]string{"type", "ref", "question"})
+	row := []string{"short_text", "name", "Name?"}
+	assert.Equal(t, row, cols.Set(row, "options", "Yes\nNo"))
+
+	cols = cols.with("options")
+	assert.Equal(t, 3, cols["options"])
+	assert.Equal(t, []string{"short_text", "name", "Name?", "Yes\nNo"}, cols.Set(row, "options", "Yes\nNo"))
+}
+
+func TestBuildForm_FillsOptionsInSheetsWithoutOptionsColumn(t *testing.T) {
+	cols, _ := ParseColumns([]string{"type", "ref", "question", "group"})
+	records := [][]string{
+		{"short_text", "name", "Name?"},
+		{"matrix", "agree", "How strongly do you agree?"},
+		{"multiple_choice", "safe", "Vaccines are safe", "agree"},
+	}
+
+	form, report, err := BuildForm("foo", cols, nil, records)
+	assert.Nil(t, err)
+	assert.Equal(t, "name", form.Fields[0].Ref)
+	assert.Equal(t, "short_text", form.Fields[0].Type)
+
+	// rows are reported for their missing options, not a missing type
+	assert.Equal(t, 2, len(report))
+	assert.Equal(t, 4, report[1].Row)
+	assert.Equal(t, "E4", report[1].Cell)
+	assert.Contains(t, report[1].Message, "multiple_choice question without options")
+}
+
+func TestBuildField_UsesColumnsFromHeader(t *testing.T) {
+	cols, _ := ParseColumns([]string{"description", "#scratch", "question", "ref", "type"})
+	i, err := BuildField(cols, []string{"desc", "ignore me", "foo", "ref", "short_text"})
+	assert.Nil(t, err)
+
+	f := i.(*Field)
+	assert.Equal(t, "ref", f.Ref)
+	assert.Equal(t, "short_text", f.Type)
+	assert.Equal(t, "foo", f.Title)
+	assert.Equal(t, "desc", f.Properties.Description)
+}
+
+func TestNewFormConf_ErrorsWithBadHeader(t *testing.T) {
+	formData := [][]string{
+		{"ref", "question"},
+		{"var1", "hello"},
+	}
+
+	_, err := NewFormConf("workspace", "form name", formData, [][]string{}, nil)
+	assert.NotNil(t, err)
+}
+
+func TestBuildField_ParsesPropertiesForOpinionScale(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "properties"})
+	i, err := BuildField(cols, []string{"ref", "opinion_scale", "foo", "steps=5; start_at_one=true; labels.left=Bad;labels.right = Good"})
+	assert.Nil(t, err)
+
+	f := i.(*Field)
+	assert.Equal(t, 5, f.Properties.Steps)
+	assert.Equal(t, true, f.Properties.StartAtOne)
+	assert.Equal(t, &FieldLabels{Left: "Bad", Right: "Good"}, f.Properties.Labels)
+}
+
+func TestBuildField_RejectsPropertiesThatDoNotApplyToType(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "properties"})
+	_, err := BuildField(cols, []string{"ref", "rating", "foo", "steps=5; start_at_one=true"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "start_at_one")
+
+	_, err = BuildField(cols, []string{"ref", "email", "foo", "steps=5"})
+	assert.NotNil(t, err)
+
+	_, err = BuildField(cols, []string{"ref", "number", "foo", "steps"})
+	assert.NotNil(t, err)
+}
+
+func TestBuildField_RejectsBadPropertyValues(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "properties"})
+	_, err := BuildField(cols, []string{"ref", "opinion_scale", "foo", "steps=five"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "steps")
+}
+
+func TestBuildField_GetsChoicesForDropdownAndRanking(t *testing.T) {
+	for _, typ := range []string{"dropdown", "ranking"} {
+		i, err := BuildField(DefaultColumns, []string{"ref", typ, "foo", "yes\nno", ""})
+		assert.Nil(t, err)
+		f := i.(*Field)
+		assert.Equal(t, []*FieldChoice{{Label: "yes"}, {Label: "no"}}, f.Properties.Choices)
+
+		_, err = BuildField(DefaultColumns, []string{"ref", typ, "foo", "", ""})
+		assert.NotNil(t, err)
+	}
+}
+
+func TestBuildField_GetsRequiredAndValidations(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "required", "validations"})
+
+	i, err := BuildField(cols, []string{"ref", "number", "foo", "yes", "min_value=0; max_value=120"})
+	assert.Nil(t, err)
+	f := i.(*Field)
+	assert.Equal(t, true, f.Validations.Required)
+	assert.Equal(t, 0, *f.Validations.MinValue)
+	assert.Equal(t, 120, *f.Validations.MaxValue)
+
+	i, err = BuildField(cols, []string{"ref", "short_text", "foo", "", "max_length=20"})
+	assert.Nil(t, err)
+	f = i.(*Field)
+	assert.Equal(t, false, f.Validations.Required)
+	assert.Equal(t, 20, f.Validations.MaxLength)
+}
+
+func TestBuildField_LeavesValidationsEmptyWhenNotSet(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "required", "validations"})
+	i, err := BuildField(cols, []string{"ref", "short_text", "foo", "no", ""})
+	assert.Nil(t, err)
+	f := i.(*Field)
+	assert.Nil(t, f.Validations)
+}
+
+func TestBuildField_RejectsValidationsThatDoNotApplyToType(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "required", "validations"})
+
+	_, err := BuildField(cols, []string{"ref", "short_text", "foo", "yes", "max_value=3"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "max_value")
+
+	_, err = BuildField(cols, []string{"ref", "statement", "foo", "yes", ""})
+	assert.NotNil(t, err)
+
+	_, err = BuildField(cols, []string{"ref", "number", "foo", "maybe", ""})
+	assert.NotNil(t, err)
+
+	_, err = BuildField(cols, []string{"ref", "number", "foo", "", "min_value=10; max_value=1"})
+	assert.NotNil(t, err)
+}
+
+func TestBuildForm_NestsQuestionsInGroups(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "options", "group"})
+	records := [][]string{
+		{"intro", "statement", "hello"},
+		{"about_you", "group", "About you"},
+		{"age", "number", "How old are you?", "", "about_you"},
+		{"gender", "multiple_choice", "Gender?", "male\nfemale", "about_you"},
+		{"bye", "short_text", "Anything else?"},
+	}
+
+	form, _, err := BuildForm("foo", cols, nil, records)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 3, len(form.Fields))
+	group := form.Fields[1]
+	assert.Equal(t, "group", group.Type)
+	assert.Equal(t, 2, len(group.Properties.Fields))
+	assert.Equal(t, "age", group.Properties.Fields[0].Ref)
+	assert.Equal(t, "gender", group.Properties.Fields[1].Ref)
+	assert.Equal(t, 2, len(group.Properties.Fields[1].Properties.Choices))
+}
+
+func TestBuildForm_SkipsQuestionsWithUnknownOrInvalidGroup(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "group"})
+	records := [][]string{
+		{"age", "number", "How old are you?", "about_you"},
+		{"intro", "statement", "hello"},
+		{"name", "short_text", "Name?", "intro"},
+		{"about_you", "group", "About you"},
+		{"inner", "group", "Nested", "about_you"},
+	}
+
+	form, _, err := BuildForm("foo", cols, nil, records)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(form.Fields))
+	assert.Equal(t, 0, len(form.Fields[1].Properties.Fields))
+}
+
+func TestBuildForm_BuildsMatrixWithSharedScale(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "options", "group"})
+	records := [][]string{
+		{"agree", "matrix", "How strongly do you agree?", "Agree\nDisagree"},
+		{"vaccines_children", "multiple_choice", "Vaccines are important for children", "", "agree"},
+		{"vaccines_safe", "multiple_choice", "Vaccines are safe", "", "agree"},
+	}
+
+	form, _, err := BuildForm("foo", cols, nil, records)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 1, len(form.Fields))
+	matrix := form.Fields[0]
+	assert.Equal(t, "matrix", matrix.Type)
+	assert.Equal(t, "How strongly do you agree?", matrix.Title)
+	assert.Equal(t, 0, len(matrix.Properties.Choices))
+	assert.Equal(t, 2, len(matrix.Properties.Fields))
+
+	for _, row := range matrix.Properties.Fields {
+		assert.Equal(t, []*FieldChoice{{Label: "Agree"}, {Label: "Disagree"}}, row.Properties.Choices)
+	}
+
+	// rows get their own choices
+	assert.False(t, matrix.Properties.Fields[0].Properties.Choices[0] == matrix.Properties.Fields[1].Properties.Choices[0])
+}
+
+func TestBuildForm_SkipsMatrixWithoutScaleOrWithBadRows(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "options", "group"})
+	records := [][]string{
+		{"empty", "matrix", "No scale"},
+		{"agree", "matrix", "How strongly do you agree?", "Agree\nDisagree"},
+		{"name", "short_text", "Name?", "", "agree"},
+	}
+
+	form, _, err := BuildForm("foo", cols, nil, records)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(form.Fields))
+	assert.Equal(t, 0, len(form.Fields[0].Properties.Fields))
+}
+
+func TestBuildForm_GetsWelcomeScreen(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "description", "button_text", "attachment"})
+	records := [][]string{
+		{"consent", "welcome_screen", "Welcome!", "Do you consent?", "I agree", "https://images.typeform.com/images/foo"},
+		{"name", "short_text", "Name?"},
+	}
+
+	form, _, err := BuildForm("foo", cols, nil, records)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(form.Fields))
+	assert.Equal(t, 1, len(form.WelcomeScreens))
+
+	ws := form.WelcomeScreens[0]
+	assert.Equal(t, "consent", ws.Ref)
+	assert.Equal(t, "Welcome!", ws.Title)
+	assert.Equal(t, &WelcomeScreenProperties{Description: "Do you consent?", ShowButton: true, ButtonText: "I agree"}, ws.Properties)
+	assert.Equal(t, &Attachment{Type: "image", Href: "https://images.typeform.com/images/foo"}, ws.Attachment)
+}
+
+func TestBuildField_ShowsWelcomeScreenButtonOnlyWithButtonText(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "button_text"})
+
+	i, err := BuildField(cols, []string{"welcome", "welcome_screen", "Welcome!"})
+	assert.Nil(t, err)
+	assert.False(t, i.(*WelcomeScreen).Properties.ShowButton)
+
+	i, err = BuildField(cols, []string{"welcome", "welcome_screen", "Welcome!", "Start"})
+	assert.Nil(t, err)
+	assert.True(t, i.(*WelcomeScreen).Properties.ShowButton)
+}
+
+func TestBuildField_GetsThankyouScreenProperties(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "button_text", "redirect_url", "share_icons", "attachment"})
+
+	i, err := BuildField(cols, []string{"ref", "thankyou_screen", "foo", "Go back", "https://example.com/?id={{hidden:id}}", "no", "https://images.typeform.com/images/foo"})
+	assert.Nil(t, err)
+	ty := i.(*ThankyouScreen)
+
+	share := false
+	assert.Equal(t, &ThankyouScreenProperties{
+		ShowButton:  true,
+		ShareIcons:  &share,
+		ButtonMode:  "redirect",
+		ButtonText:  "Go back",
+		RedirectUrl: "https://example.com/?id={{hidden:id}}",
+	}, ty.Properties)
+	assert.Equal(t, "https://images.typeform.com/images/foo", ty.Attachment.Href)
+
+	i, _ = BuildField(cols, []string{"ref", "thankyou_screen", "foo"})
+	assert.Nil(t, i.(*ThankyouScreen).Properties)
+
+	_, err = BuildField(cols, []string{"ref", "thankyou_screen", "foo", "", "example.com"})
+	assert.NotNil(t, err)
+	assert.Equal(t, "E2", newRowError(cols, 2, err).Cell)
+
+	_, err = BuildField(cols, []string{"ref", "thankyou_screen", "foo", "", "", "maybe"})
+	assert.NotNil(t, err)
+	assert.Equal(t, "F2", newRowError(cols, 2, err).Cell)
+}
+
+func TestBuildForm_SkipsThankyouScreensRedirectingWithUnknownHidden(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "redirect_url"})
+	records := [][]string{
+		{"good", "thankyou_screen", "Bye", "https://example.com/?id={{hidden:id}}"},
+		{"bad", "thankyou_screen", "Bye", "https://example.com/?id={{hidden:foo}}"},
+		{"id", "hidden", "id"},
+	}
+
+	form, _, err := BuildForm("foo", cols, nil, records)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(form.ThankYouScreens))
+	assert.Equal(t, "good", form.ThankYouScreens[0].Ref)
+}
+
+func TestBuildField_GetsChoiceFlags(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "options", "properties", "validations"})
+
+	i, err := BuildField(cols, []string{"ref", "multiple_choice", "foo", "yes\nno", "allow_multiple_selection=true; randomize=false; allow_other_choice=yes; vertical_alignment=true", "max_selection=2"})
+	assert.Nil(t, err)
+	f := i.(*Field)
+	assert.Equal(t, true, *f.Properties.AllowMultipleSelection)
+	assert.Equal(t, false, *f.Properties.Randomize)
+	assert.Equal(t, true, *f.Properties.VerticalAlignment)
+	assert.Nil(t, f.Properties.AlphabeticalOrder)
+	assert.Equal(t, 2, f.Validations.MaxSelection)
+}
+
+func TestBuildField_RejectsChoiceFlagsThatDoNotApply(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "options", "properties", "validations"})
+
+	_, err := BuildField(cols, []string{"ref", "multiple_choice", "foo", "yes\nno", "alphabetical_order=true"})
+	assert.NotNil(t, err)
+
+	_, err = BuildField(cols, []string{"ref", "dropdown", "foo", "yes\nno", "alphabetical_order=true; randomize=true"})
+	assert.Nil(t, err)
+
+	_, err = BuildField(cols, []string{"ref", "multiple_choice", "foo", "yes\nno", "", "max_selection=2"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "allow_multiple_selection")
+}
+
+func TestBuildField_GetsPictureChoices(t *testing.T) {
+	i, err := BuildField(DefaultColumns, []string{"ref", "picture_choice", "foo", "Cat | images/cat.png\nDog|https://example.com/dog.jpg", ""})
+	assert.Nil(t, err)
+	f := i.(*Field)
+	assert.Equal(t, "foo", f.Title)
+	assert.Equal(t, []*FieldChoice{
+		{Label: "Cat", Attachment: &Attachment{Type: "image", Href: "images/cat.png"}},
+		{Label: "Dog", Attachment: &Attachment{Type: "image", Href: "https://example.com/dog.jpg"}},
+	}, f.Properties.Choices)
+
+	_, err = BuildField(DefaultColumns, []string{"ref", "picture_choice", "foo", "Cat\nDog", ""})
+	assert.NotNil(t, err)
+}
+
+func TestBuildField_GetsAttachments(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "attachment", "attachment_properties"})
+
+	i, err := BuildField(cols, []string{"ref", "short_text", "foo", "images/cat.png", "layout=split; placement=left; description=A cat"})
+	assert.Nil(t, err)
+	f := i.(*Field)
+	assert.Equal(t, &Attachment{Type: "image", Href: "images/cat.png", Properties: &AttachmentProperties{Description: "A cat"}}, f.Attachment)
+	assert.Equal(t, "split", f.Layout.Type)
+	assert.Equal(t, "left", f.Layout.Placement)
+	assert.Equal(t, f.Attachment, f.Layout.Attachment)
+
+	i, err = BuildField(cols, []string{"ref", "statement", "foo", "https://www.youtube.com/watch?v=abc", "scale=0.6"})
+	assert.Nil(t, err)
+	f = i.(*Field)
+	assert.Equal(t, &Attachment{Type: "video", Href: "https://www.youtube.com/watch?v=abc", Scale: 0.6}, f.Attachment)
+	assert.Nil(t, f.Layout)
+
+	i, err = BuildField(cols, []string{"ref", "statement", "foo", "https://vimeo.com/123"})
+	assert.Nil(t, err)
+	assert.Equal(t, "video", i.(*Field).Attachment.Type)
+}
+
+func TestBuildField_RejectsBadAttachmentProperties(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "attachment", "attachment_properties"})
+
+	bad := [][]string{
+		{"ref", "short_text", "foo", "cat.png", "scale=0.5"},
+		{"ref", "short_text", "foo", "cat.png", "layout=sideways"},
+		{"ref", "short_text", "foo", "cat.png", "placement=left"},
+		{"ref", "short_text", "foo", "https://youtu.be/abc", "layout=split"},
+		{"ref", "short_text", "foo", "", "layout=split"},
+		{"ref", "short_text", "foo", "cat.png", "size=big"},
+	}
+
+	for _, row := range bad {
+		_, err := BuildField(cols, row)
+		assert.NotNil(t, err, row)
+	}
+}
+
+func TestBuildField_GetsChoiceScores(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "options", "score"})
+
+	field, err := BuildField(cols, []string{"q", "multiple_choice", "Capital?", "Paris\nLyon", "paris=1"})
+	assert.Nil(t, err)
+	f := field.(*Field)
+	assert.Equal(t, 1.0, f.Properties.Choices[0].Score)
+	assert.Equal(t, 0.0, f.Properties.Choices[1].Score)
+
+	// scores are not sent to Typeform
+	b, _ := json.Marshal(f)
+	assert.NotContains(t, string(b), "score")
+
+	_, err = BuildField(cols, []string{"q", "multiple_choice", "Capital?", "Paris\nLyon", "Rome=1"})
+	assert.NotNil(t, err)
+
+	_, err = BuildField(cols, []string{"q", "short_text", "Capital?", "", "Paris=1"})
+	assert.NotNil(t, err)
+}
+
+func TestBuildField_GetsChoiceRefsFromChoiceRefsColumn(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "options", "choice_refs"})
+
+	i, err := BuildField(cols, []string{"q", "multiple_choice", "foo", "Strongly agree\nDisagree\nOther", "agree\n dis-agree_2 \n"})
+	assert.Nil(t, err)
+	choices := i.(*Field).Properties.Choices
+	assert.Equal(t, "Strongly agree", choices[0].Label)
+	assert.Equal(t, "agree", choices[0].Ref)
+	assert.Equal(t, "dis-agree_2", choices[1].Ref)
+	assert.Equal(t, "", choices[2].Ref)
+
+	// refs are not added to the title
+	i, err = BuildField(cols, []string{"q", "multiple_choice", "foo", "A. yes\nB. no", "yes\nno"})
+	assert.Nil(t, err)
+	f := i.(*Field)
+	assert.Equal(t, "foo\n\nA. yes\nB. no", f.Title)
+	assert.Equal(t, "no", f.Properties.Choices[1].Ref)
+
+	i, err = BuildField(cols, []string{"q", "picture_choice", "foo", "Cat | https://example.com/cat.png", "cat"})
+	assert.Nil(t, err)
+	assert.Equal(t, "cat", i.(*Field).Properties.Choices[0].Ref)
+
+	_, err = BuildField(cols, []string{"q", "multiple_choice", "foo", "Yes\nOf course", "yes\nyes"})
+	assert.NotNil(t, err)
+
+	_, err = BuildField(cols, []string{"q", "multiple_choice", "foo", "Yes\nNo\nMaybe", "yes\nno"})
+	assert.NotNil(t, err)
+	assert.Equal(t, "E2", newRowError(cols, 2, err).Cell)
+
+	_, err = BuildField(cols, []string{"q", "multiple_choice", "foo", "Yes\nNo", "yes\nnot sure"})
+	assert.NotNil(t, err)
+}
+
+func TestBuildField_KeepsBracketsInLabels(t *testing.T) {
+	i, err := BuildField(DefaultColumns, []string{"q", "multiple_choice", "foo", "Yes\nOther [specify]", ""})
+	assert.Nil(t, err)
+	choices := i.(*Field).Properties.Choices
+	assert.Equal(t, "Other [specify]", choices[1].Label)
+	assert.Equal(t, "", choices[1].Ref)
+
+	i, err = BuildField(DefaultColumns, []string{"q", "picture_choice", "foo", "Cat [tabby] | https://example.com/cat.png", ""})
+	assert.Nil(t, err)
+	assert.Equal(t, "Cat [tabby]", i.(*Field).Properties.Choices[0].Label)
+}
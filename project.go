@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+type ProjectTab struct {
+	Tab string `json:"tab"`
+}
+
+type ProjectFile struct {
+	Lang string `json:"lang"`
+	Path string `json:"path"`
+}
+
+// Project is a survey with its translations, such as test/config_a.yaml
+type Project struct {
+	Workspace        string        `json:"workspace"`
+	Name             string        `json:"name"`
+	Forms            []ProjectTab  `json:"forms"`
+	Messages         ProjectTab    `json:"messages"`
+	BaseFile         ProjectFile   `json:"baseFile"`
+	TranslationFiles []ProjectFile `json:"translationFiles"`
+
+	// id or name of the theme of every form, see ApplyTheme
+	Theme string `json:"theme"`
+}
+
+// language codes by name, for the languages of project files
+var languageNames = map[string]string{
+	"arabic":      "ar",
+	"armenian":    "hy",
+	"azerbaijani": "az",
+	"bengali":     "bn",
+	"bulgarian":   "bg",
+	"catalan":     "ca",
+	"chinese":     "zh",
+	"croatian":    "hr",
+	"czech":       "cs",
+	"danish":      "da",
+	"dutch":       "nl",
+	"english":     "en",
+	"estonian":    "et",
+	"finnish":     "fi",
+	"french":      "fr",
+	"georgian":    "ka",
+	"german":      "de",
+	"greek":       "el",
+	"hebrew":      "he",
+	"hindi":       "hi",
+	"hungarian":   "hu",
+	"indonesian":  "id",
+	"italian":     "it",
+	"japanese":    "ja",
+	"korean":      "ko",
+	"latvian":     "lv",
+	"lithuanian":  "lt",
+	"norwegian":   "no",
+	"persian":     "fa",
+	"polish":      "pl",
+	"portuguese":  "pt",
+	"romanian":    "ro",
+	"russian":     "ru",
+	"serbian":     "sr",
+	"slovak":      "sk",
+	"slovenian":   "sl",
+	"spanish":     "es",
+	"swahili":     "sw",
+	"swedish":     "sv",
+	"thai":        "th",
+	"turkish":     "tr",
+	"ukrainian":   "uk",
+	"urdu":        "ur",
+	"vietnamese":  "vi",
+}
+
+// languageFromName reads a language name, such as Turkish, or code
+func languageFromName(lang string) (string, error) {
+	if code, ok := languageNames[strings.ToLower(strings.TrimSpace(lang))]; ok {
+		return code, nil
+	}
+
+	code, err := parseLanguage(lang)
+	if err != nil {
+		return "", fmt.Errorf("Unknown language %s, use its name or its code", lang)
+	}
+	return code, nil
+}
+
+// LoadProject reads a project file. The paths of its files are
+// relative to the project file.
+func LoadProject(path string) (*Project, error) {
+	p := new(Project)
+	err := readDefinition(path, p)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Workspace == "" || p.Name == "" || p.BaseFile.Path == "" {
+		return nil, fmt.Errorf("Project %s needs a workspace, a name and a baseFile with a path", path)
+	}
+
+	files := []*ProjectFile{&p.BaseFile}
+	for i := range p.TranslationFiles {
+		files = append(files, &p.TranslationFiles[i])
+	}
+
+	for _, f := range files {
+		if f.Path == "" {
+			return nil, fmt.Errorf("Project %s has a file without a path", path)
+		}
+
+		if f.Lang != "" {
+			if _, err := languageFromName(f.Lang); err != nil {
+				return nil, fmt.Errorf("Project %s: %w", path, err)
+			}
+		}
+
+		if _, ok := spreadsheetID(f.Path); !ok && !filepath.IsAbs(f.Path) {
+			f.Path = filepath.Join(filepath.Dir(path), f.Path)
+		}
+	}
+
+	for i, f := range p.TranslationFiles {
+		if f.Lang == "" {
+			return nil, fmt.Errorf("Project %s: translation file %d (%s) needs a lang", path, i+1, f.Path)
+		}
+	}
+
+	return p, nil
+}
+
+func (p *Project) surveyFile(f ProjectFile, name string) *SurveyFile {
+	survey := NewSurveyFile(p.Workspace, f.Path)
+	survey.BaseName = name
+	survey.MessagesTab = p.Messages.Tab
+	for _, t := range p.Forms {
+		survey.Tabs = append(survey.Tabs, t.Tab)
+	}
+	return survey
+}
+
+// Base is the survey of the base file. Its forms are named after the
+// project, such as "Routine Immunization - Baseline".
+func (p *Project) Base() *SurveyFile {
+	return p.surveyFile(p.BaseFile, p.Name)
+}
+
+// Translation is the survey of a translation file. Its forms are named
+// after the project and the language, such as
+// "Routine Immunization Turkish - Baseline".
+func (p *Project) Translation(f ProjectFile) *SurveyFile {
+	survey := p.surveyFile(f, fmt.Sprintf("%s %s", p.Name, f.Lang))
+	survey.Translation = true
+	return survey
+}
+
+// useLanguage sets the language of the forms that don't set one themselves
+func useLanguage(formConfs map[string]*FormConf, language string) {
+	if language == "" {
+		return
+	}
+
+	for _, c := range formConfs {
+		if c.Form.Settings == nil {
+			c.Form.Settings = &FormSettings{}
+		}
+		if c.Form.Settings.Language == "" {
+			c.Form.Settings.Language = language
+		}
+	}
+}
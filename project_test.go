@@ -0,0 +1,86 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadProject(t *testing.T) {
+	p, err := LoadProject("test/config_a.yaml")
+	assert.Nil(t, err)
+
+	assert.Equal(t, "foo", p.Workspace)
+	assert.Equal(t, "Routine Immunization", p.Name)
+	assert.Equal(t, []ProjectTab{{"Baseline"}, {"Payment"}, {"Endline"}}, p.Forms)
+	assert.Equal(t, "Messages", p.Messages.Tab)
+	assert.Equal(t, filepath.Join("test", "eng.xlsx"), p.BaseFile.Path)
+	assert.Equal(t, 2, len(p.TranslationFiles))
+	assert.Equal(t, filepath.Join("test", "tur.xlsx"), p.TranslationFiles[1].Path)
+
+	base := p.Base()
+	assert.Equal(t, "Routine Immunization", base.BaseName)
+	assert.Equal(t, []string{"Baseline", "Payment", "Endline"}, base.Tabs)
+
+	translation := p.Translation(p.TranslationFiles[0])
+	assert.Equal(t, "Routine Immunization Armenian", translation.BaseName)
+
+	lang, _ := languageFromName(p.TranslationFiles[0].Lang)
+	assert.Equal(t, "hy", lang)
+}
+
+func TestLoadProject_ErrorsOnBadProjects(t *testing.T) {
+	projects := []string{
+		"workspace: foo\nbaseFile:\n  path: eng.xlsx\n",
+		"workspace: foo\nname: bar\n",
+		"workspace: foo\nname: bar\nbaseFile:\n  path: eng.xlsx\ntranslationFiles:\n  - path: tur.xlsx\n",
+		"workspace: foo\nname: bar\nbaseFile:\n  path: eng.xlsx\ntranslationFiles:\n  - lang: Klingon\n    path: tlh.xlsx\n",
+	}
+
+	for _, project := range projects {
+		path := filepath.Join(t.TempDir(), "project.yaml")
+		ioutil.WriteFile(path, []byte(project), 0644)
+
+		_, err := LoadProject(path)
+		assert.NotNil(t, err, project)
+	}
+}
+
+func TestInitialForms_OnlyUsesTheTabsOfTheProject(t *testing.T) {
+	dir := t.TempDir()
+	writeWorkbook(t, filepath.Join(dir, "eng.xlsx"), []testSheet{
+		{"Baseline", [][]string{{"ref", "type", "question"}, {"name", "short_text", "Name?"}}},
+		{"Scratch", [][]string{{"whatever"}}},
+		{"Texts", [][]string{{"variable", "message"}, {"label.buttonHint.default", "Press enter"}}},
+	})
+
+	path := filepath.Join(dir, "project.yaml")
+	ioutil.WriteFile(path, []byte(`
+workspace: foo
+name: Routine Immunization
+forms:
+  - tab: Baseline
+messages:
+  tab: Texts
+baseFile:
+  lang: English
+  path: eng.xlsx
+`), 0644)
+
+	p, err := LoadProject(path)
+	assert.Nil(t, err)
+
+	forms, _, err := p.Base().InitialForms()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(forms))
+	assert.Equal(t, "Routine Immunization - Baseline", forms["Baseline"].Name)
+	assert.Equal(t, "Press enter", forms["Baseline"].MessagesData[1][1])
+
+	survey := p.Base()
+	survey.Tabs = []string{"Baseline", "Payment"}
+	_, _, err = survey.InitialForms()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Could not find tab Payment")
+}
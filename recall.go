@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// recall placeholders, such as {{field:name}} or {{hidden:country}}
+var recallPlaceholder = regexp.MustCompile(`{{\s*([a-z_]+):([^}\s]+)\s*}}`)
+
+// recallScope is what a piece of text can recall: the questions
+// before it and the hidden fields of the form.
+type recallScope struct {
+	before map[string]bool
+	fields map[string]bool
+	hidden map[string]bool
+}
+
+func newRecallScope(form *Form) *recallScope {
+	scope := &recallScope{map[string]bool{}, map[string]bool{}, map[string]bool{}}
+	for _, f := range flattenFields(form.Fields) {
+		scope.fields[f.Ref] = true
+	}
+	for _, h := range form.Hidden {
+		scope.hidden[string(h)] = true
+	}
+	return scope
+}
+
+func (s *recallScope) check(where, text string) []string {
+	problems := []string{}
+
+	for _, match := range recallPlaceholder.FindAllStringSubmatch(text, -1) {
+		kind, name := match[1], match[2]
+
+		switch kind {
+		case "field":
+			if !s.fields[name] {
+				problems = append(problems, fmt.Sprintf("%s recalls question %s, which is not in the form", where, name))
+			} else if !s.before[name] {
+				problems = append(problems, fmt.Sprintf("%s recalls question %s, which is not answered before it", where, name))
+			}
+		case "hidden":
+			if !s.hidden[name] {
+				problems = append(problems, fmt.Sprintf("%s recalls hidden field %s, which is not declared with a hidden row", where, name))
+			}
+		case "var":
+			if !allows(logicVariables, name) {
+				problems = append(problems, fmt.Sprintf("%s recalls variable %s, which should be one of %s", where, name, strings.Join(logicVariables, ", ")))
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("%s has an unknown recall %s", where, match[0]))
+		}
+	}
+
+	return problems
+}
+
+// recallText is a text of a question or screen, by the
+// column of the survey sheet it comes from
+type recallText struct {
+	column string
+	text   string
+}
+
+func fieldTexts(f *Field) []recallText {
+	texts := []recallText{{"question", f.Title}}
+	if f.Properties == nil {
+		return texts
+	}
+
+	texts = append(texts, recallText{"description", f.Properties.Description}, recallText{"button_text", f.Properties.ButtonText})
+	for _, c := range f.Properties.Choices {
+		texts = append(texts, recallText{"options", c.Label})
+	}
+	return texts
+}
+
+// recallProblem is a bad recall in a text of the question
+// or screen with the ref
+type recallProblem struct {
+	ref     string
+	column  string
+	message string
+}
+
+func recallProblems(form *Form) []recallProblem {
+	scope := newRecallScope(form)
+	problems := []recallProblem{}
+
+	check := func(kind, ref string, texts []recallText) {
+		for _, t := range texts {
+			for _, message := range scope.check(kind+" "+ref, t.text) {
+				problems = append(problems, recallProblem{ref, t.column, message})
+			}
+		}
+	}
+
+	// welcome screens come before any question
+	for _, s := range form.WelcomeScreens {
+		texts := []recallText{{"question", s.Title}}
+		if s.Properties != nil {
+			texts = append(texts, recallText{"description", s.Properties.Description}, recallText{"button_text", s.Properties.ButtonText})
+		}
+		check("Welcome screen", s.Ref, texts)
+	}
+
+	for _, f := range flattenFields(form.Fields) {
+		check("Question", f.Ref, fieldTexts(f))
+		scope.before[f.Ref] = true
+	}
+
+	// thankyou screens come after every question
+	for _, s := range form.ThankYouScreens {
+		texts := []recallText{{"question", s.Title}}
+		if s.Properties != nil {
+			texts = append(texts, recallText{"button_text", s.Properties.ButtonText})
+		}
+		check("Thankyou screen", s.Ref, texts)
+	}
+
+	return problems
+}
+
+// CheckRecall makes sure every recall in the texts of the form points to
+// a question answered before the text is shown, or to a hidden field.
+func CheckRecall(form *Form) error {
+	problems := []string{}
+	for _, p := range recallProblems(form) {
+		problems = append(problems, p.message)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// messagesRecallReport lists the rows of the messages with a recall
+// that does not point to a question or a hidden field of the form.
+func messagesRecallReport(form *Form, messagesData [][]string) ErrorReport {
+	scope := newRecallScope(form)
+	scope.before = scope.fields
+
+	cols := Columns{"variable": 0, "message": 1}
+	report := ErrorReport{}
+	for i, r := range messagesData {
+		if i == 0 {
+			continue
+		}
+		for _, p := range scope.check("Message "+get(r, 0), get(r, 1)) {
+			report = append(report, sheetError("Messages", cols, i+1, "message", fmt.Errorf("%s", p)))
+		}
+	}
+	return report
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func recallForm(rows ...[]string) (*Form, error) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "options", "description"})
+	records := append([][]string{
+		{"name", "short_text", "What is your name?"},
+		{"country", "hidden", "country"},
+	}, rows...)
+	form, _, err := BuildForm("foo", cols, nil, records)
+	return form, err
+}
+
+func TestBuildForm_AllowsRecallOfEarlierQuestionsAndHiddenFields(t *testing.T) {
+	form, err := recallForm(
+		[]string{"age", "number", "How old are you, {{field:name}}?", "", "From {{ hidden:country }}"},
+		[]string{"fruit", "multiple_choice", "Which one?", "{{field:name}}'s choice\nOther"},
+		[]string{"bye", "thankyou_screen", "Bye {{field:name}}, your score is {{var:score}}"},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(form.Fields))
+}
+
+func TestBuildForm_FailsOnBadRecalls(t *testing.T) {
+	rows := [][]string{
+		{"age", "number", "How old are you, {{field:nmae}}?"},
+		{"age", "number", "How old are you, {{field:age}}?"},
+		{"age", "number", "How old?", "", "From {{hidden:city}}"},
+		{"fruit", "multiple_choice", "Which one?", "{{field:later}}\nOther"},
+		{"hi", "welcome_screen", "Welcome {{field:name}}"},
+		{"age", "number", "Your {{var:points}}?"},
+		{"age", "number", "Your {{form:id}}?"},
+	}
+
+	for _, row := range rows {
+		_, err := recallForm(row, []string{"later", "short_text", "Later"})
+		assert.NotNil(t, err, row[2])
+	}
+}
+
+func TestBuildForm_ReportsEveryBadRecall(t *testing.T) {
+	_, err := recallForm(
+		[]string{"age", "number", "How old are you, {{field:nmae}}?"},
+		[]string{"city", "short_text", "City in {{hidden:contry}}?"},
+	)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Question age recalls question nmae, which is not in the form")
+	assert.Contains(t, err.Error(), "Question city recalls hidden field contry, which is not declared with a hidden row")
+}
+
+func TestNewFormConf_ChecksRecallInMessages(t *testing.T) {
+	formData := [][]string{
+		{"ref", "type", "question"},
+		{"name", "short_text", "Name?"},
+	}
+
+	_, err := NewFormConf("workey", "foo", formData, [][]string{
+		{"variable", "message"},
+		{"label.buttonHint.default", "Thanks {{field:name}}"},
+	}, nil)
+	assert.Nil(t, err)
+
+	conf, err := NewFormConf("workey", "foo", formData, [][]string{
+		{"variable", "message"},
+		{"label.buttonHint.default", "Thanks {{field:nombre}}"},
+		{"label.buttonHint.longtext", "Press Enter"},
+	}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"variable", "message"}, {"label.buttonHint.longtext", "Press Enter"}}, conf.MessagesData)
+
+	assert.Equal(t, 1, len(conf.Errors))
+	e := conf.Errors[0]
+	assert.Equal(t, "Messages", e.Sheet)
+	assert.Equal(t, 2, e.Row)
+	assert.Equal(t, "B2", e.Cell)
+	assert.Contains(t, e.Message, "Message label.buttonHint.default")
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// RowError is a problem with a row of a sheet, which
+// left the row out of its form
+type RowError struct {
+	File    string
+	Sheet   string
+	Row     int
+	Column  string
+	Cell    string
+	Message string
+
+	// path of the file, for the annotated copy
+	path string
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("%s sheet, row %d: %s", e.Sheet, e.Row, e.Message)
+}
+
+// ErrorReport lists the rows left out of the forms of a survey
+type ErrorReport []*RowError
+
+// Print writes the report as a table, if there is anything in it
+func (r ErrorReport) Print(w io.Writer) {
+	if len(r) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "%d rows were left out of the forms:\n", len(r))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tSHEET\tROW\tCELL\tPROBLEM")
+	for _, e := range r {
+		message := strings.Join(strings.Fields(e.Message), " ")
+		row := ""
+		if e.Row > 0 {
+			row = fmt.Sprint(e.Row)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.File, e.Sheet, row, e.Cell, message)
+	}
+	tw.Flush()
+}
+
+// in sets the file of every error of the report, and the sheet
+// of those that are not in one of the special sheets
+func (r ErrorReport) in(path, sheet string) ErrorReport {
+	for _, e := range r {
+		e.File = filepath.Base(path)
+		e.path = path
+		if e.Sheet == "" {
+			e.Sheet = sheet
+		}
+	}
+	return r
+}
+
+// unique leaves out the errors already in the report, as rows of
+// the special sheets are checked for every form
+func (r ErrorReport) unique() ErrorReport {
+	res := ErrorReport{}
+	seen := map[RowError]bool{}
+	for _, e := range r {
+		if !seen[*e] {
+			seen[*e] = true
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// asError is the report as a ReportError, or nil if it is empty
+func (r ErrorReport) asError() error {
+	if len(r) == 0 {
+		return nil
+	}
+	return &ReportError{r}
+}
+
+// columnError is an error in one column of a row
+type columnError struct {
+	column string
+	err    error
+}
+
+func (e *columnError) Error() string {
+	return e.err.Error()
+}
+
+func (e *columnError) Unwrap() error {
+	return e.err
+}
+
+func atColumn(column string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &columnError{column, err}
+}
+
+// newRowError is the error of a row of a survey sheet, at
+// the cell of the column the error is in, if any
+func newRowError(cols Columns, row int, err error) *RowError {
+	e := &RowError{Row: row, Message: err.Error()}
+
+	var ce *columnError
+	if errors.As(err, &ce) {
+		e.Column = ce.column
+		if i, ok := cols[ce.column]; ok {
+			e.Cell, _ = excelize.CoordinatesToCellName(i+1, row)
+		}
+	}
+	return e
+}
+
+// sheetError is the error of a row of one of the special sheets,
+// such as the Logic sheet, at the cell of the column, if any
+func sheetError(sheet string, cols Columns, row int, column string, err error) *RowError {
+	e := newRowError(cols, row, atColumn(column, err))
+	e.Sheet = sheet
+	return e
+}
+
+// ReportError is the error of rows that cannot be used, such as the
+// rows that keep a form from being built at all
+type ReportError struct {
+	Report ErrorReport
+}
+
+func (e *ReportError) Error() string {
+	messages := []string{}
+	for _, r := range e.Report {
+		if r.Sheet != "" {
+			messages = append(messages, r.Error())
+		} else {
+			messages = append(messages, r.Message)
+		}
+	}
+	return strings.Join(messages, "\n")
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildForm_ReportsRowsLeftOut(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "options", "properties", "group"})
+	form, report, err := BuildForm("foo", cols, nil, [][]string{
+		{"name", "short_text", "Name?"},
+		{"fruit", "multiple_choice", "Fruit?"},
+		{},
+		{"scale", "opinion_scale", "How much?", "", "steps=many"},
+		{"", "short_text", "No ref?"},
+		{"city", "short_text", "City?", "", "", "nowhere"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(form.Fields))
+
+	cells := []string{}
+	rows := []int{}
+	for _, e := range report {
+		cells = append(cells, e.Cell)
+		rows = append(rows, e.Row)
+	}
+	assert.Equal(t, []string{"D3", "E5", "A6", "F7"}, cells)
+	assert.Equal(t, []int{3, 5, 6, 7}, rows)
+	assert.Equal(t, "options", report[0].Column)
+	assert.Contains(t, report[3].Message, "there is no group with that ref")
+}
+
+func TestBuildForm_ReportsUnknownTypesAndRepeatedRefs(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question"})
+	form, report, err := BuildForm("foo", cols, nil, [][]string{
+		{"a", "shortest_text", "Hi"},
+		{"b", "short_text", "Hello"},
+		{"b", "long_text", "Again"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(form.Fields))
+
+	assert.Equal(t, 2, len(report))
+	assert.Equal(t, "B2", report[0].Cell)
+	assert.Contains(t, report[0].Message, "Unknown question type shortest_text")
+	assert.Equal(t, "A4", report[1].Cell)
+	assert.Contains(t, report[1].Message, "The ref b is already used in row 3")
+}
+
+func TestInitialForms_ReportsRowsOfEverySheet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "survey.xlsx")
+	writeWorkbook(t, path, []testSheet{
+		{"Baseline", [][]string{
+			{"ref", "type", "question", "options"},
+			{"fruit", "multiple_choice", "Fruit?"},
+		}},
+		{"Endline", [][]string{
+			{"ref", "type", "question"},
+			{"name", "short_text", ""},
+		}},
+		{"Messages", [][]string{{"variable", "message"}}},
+	})
+
+	_, report, err := NewSurveyFile("workey", path).InitialForms()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(report))
+
+	sheets := map[string]string{}
+	for _, e := range report {
+		assert.Equal(t, "survey.xlsx", e.File)
+		sheets[e.Sheet] = e.Cell
+	}
+	assert.Equal(t, map[string]string{"Baseline": "D2", "Endline": "C2"}, sheets)
+
+	endline, err := checkReport(report, "Endline", false)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(endline))
+
+	_, err = checkReport(report, "", true)
+	assert.NotNil(t, err)
+}
+
+func TestErrorReport_PrintsTable(t *testing.T) {
+	report := ErrorReport{
+		{File: "survey.xlsx", Sheet: "Baseline", Row: 3, Cell: "D3", Message: "multiple_choice question without options!\nSkipping."},
+		{File: "survey.xlsx", Sheet: "Endline", Row: 12, Message: "The group g cannot be put inside of another group (h)"},
+	}
+
+	b := new(bytes.Buffer)
+	report.Print(b)
+	assert.Equal(t, `2 rows were left out of the forms:
+FILE         SHEET     ROW  CELL  PROBLEM
+survey.xlsx  Baseline  3    D3    multiple_choice question without options! Skipping.
+survey.xlsx  Endline   12         The group g cannot be put inside of another group (h)
+`, b.String())
+
+	b.Reset()
+	ErrorReport{}.Print(b)
+	assert.Equal(t, "", b.String())
+}
+
+func writeSurveyWithBadRows(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "survey.xlsx")
+	writeWorkbook(t, path, []testSheet{
+		{"Baseline", [][]string{
+			{"ref", "type", "question", "options"},
+			{"consent", "multiple_choice", "Consent?", "Yes\nNo"},
+			{"fruit", "multiple_choice", "Fruit?"},
+			{"name", "short_text", "Name?"},
+		}},
+		{"Messages", [][]string{
+			{"variable", "message"},
+			{"label.buttonHint.default", "Thanks {{field:nmae}}"},
+		}},
+		{"Logic", [][]string{
+			{"form", "field", "choice", "jump_to"},
+			{"Baseline", "consent", "No", "nowhere"},
+		}},
+		{"Settings", [][]string{
+			{"setting", "value"},
+			{"language", "klingon"},
+		}},
+	})
+	return path
+}
+
+func TestInitialForms_ReportsRowsOfTheSpecialSheets(t *testing.T) {
+	path := writeSurveyWithBadRows(t)
+
+	forms, report, err := NewSurveyFile("workey", path).InitialForms()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(forms["Baseline"].Form.Fields))
+	assert.Nil(t, forms["Baseline"].Form.Logic)
+
+	rows := map[string]int{}
+	for _, e := range report {
+		rows[e.Sheet] = e.Row
+	}
+	assert.Equal(t, map[string]int{"Baseline": 3, "Messages": 2, "Logic": 2, "Settings": 2}, rows)
+}
+
+func TestRunBaseCreate_UploadsNothingWhenStrict(t *testing.T) {
+	path := writeSurveyWithBadRows(t)
+
+	calls := 0
+	ts, _ := testServer(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	})
+	defer ts.Close()
+
+	uploader := TypeformUploader{BaseUrl: ts.URL, TypeformToken: "secret"}
+	err := runBaseCreate(uploader, "workey", path, "", "", false, true)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "--strict")
+	assert.Equal(t, 0, calls)
+}
+
+func TestRunBaseCreate_UploadsTheOtherRows(t *testing.T) {
+	path := writeSurveyWithBadRows(t)
+
+	body := ""
+	ts, _ := testServer(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/forms":
+			fmt.Fprintf(w, `{"items": []}`)
+		case r.Method == "POST" && r.URL.Path == "/forms":
+			b, _ := ioutil.ReadAll(r.Body)
+			body = string(b)
+			w.Header().Set("Location", "https://api.typeform.com/forms/foobar")
+			w.WriteHeader(201)
+		default:
+			w.WriteHeader(204)
+		}
+	})
+	defer ts.Close()
+
+	uploader := TypeformUploader{BaseUrl: ts.URL, TypeformToken: "secret"}
+	err := runBaseCreate(uploader, "workey", path, "", "", false, false)
+	assert.Nil(t, err)
+
+	assert.True(t, strings.Contains(body, `"ref":"consent"`))
+	assert.True(t, strings.Contains(body, `"ref":"name"`))
+	assert.False(t, strings.Contains(body, `"ref":"fruit"`))
+	assert.False(t, strings.Contains(body, "klingon"))
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type FormSettingsMeta struct {
+	Title         string `json:"title,omitempty"`
+	Description   string `json:"description,omitempty"`
+	AllowIndexing *bool  `json:"allow_indexing,omitempty"`
+}
+
+// FormSettings are the settings of a form. Everything is optional, so
+// that settings from a sheet can be laid over the settings in Typeform.
+type FormSettings struct {
+	Language                string            `json:"language,omitempty"`
+	ProgressBar             string            `json:"progress_bar,omitempty"`
+	ShowProgressBar         *bool             `json:"show_progress_bar,omitempty"`
+	IsPublic                *bool             `json:"is_public,omitempty"`
+	HideNavigation          *bool             `json:"hide_navigation,omitempty"`
+	ShowTypeformBranding    *bool             `json:"show_typeform_branding,omitempty"`
+	ShowTimeToComplete      *bool             `json:"show_time_to_complete,omitempty"`
+	ShowNumberOfSubmissions *bool             `json:"show_number_of_submissions,omitempty"`
+	ShowCookieConsent       *bool             `json:"show_cookie_consent,omitempty"`
+	RedirectAfterSubmitUrl  string            `json:"redirect_after_submit_url,omitempty"`
+	Meta                    *FormSettingsMeta `json:"meta,omitempty"`
+
+	// id or name of the theme, see ApplyTheme
+	Theme string `json:"-"`
+
+	// every setting read from Typeform, such as capabilities, which
+	// the fields above don't have but an update must send back
+	raw map[string]json.RawMessage
+}
+
+// formSettings is FormSettings without its JSON methods
+type formSettings FormSettings
+
+// mergeJSON lays the value over the base, key by key if both are objects
+func mergeJSON(base, value json.RawMessage) json.RawMessage {
+	a, b := map[string]json.RawMessage{}, map[string]json.RawMessage{}
+	if json.Unmarshal(base, &a) != nil || json.Unmarshal(value, &b) != nil {
+		return value
+	}
+
+	for k, v := range b {
+		if old, ok := a[k]; ok {
+			v = mergeJSON(old, v)
+		}
+		a[k] = v
+	}
+
+	res, err := json.Marshal(a)
+	if err != nil {
+		return value
+	}
+	return res
+}
+
+// UnmarshalJSON reads the settings, keeping those the struct doesn't have
+func (s *FormSettings) UnmarshalJSON(b []byte) error {
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, (*formSettings)(s)); err != nil {
+		return err
+	}
+
+	if s.raw == nil {
+		s.raw = map[string]json.RawMessage{}
+	}
+	for k, v := range raw {
+		if old, ok := s.raw[k]; ok {
+			v = mergeJSON(old, v)
+		}
+		s.raw[k] = v
+	}
+	return nil
+}
+
+// MarshalJSON writes the settings read from Typeform with
+// the settings of the struct laid over them
+func (s FormSettings) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(formSettings(s))
+	if err != nil || len(s.raw) == 0 {
+		return b, err
+	}
+
+	raw, err := json.Marshal(s.raw)
+	if err != nil {
+		return nil, err
+	}
+	return mergeJSON(raw, b), nil
+}
+
+func (s *FormSettings) meta() *FormSettingsMeta {
+	if s.Meta == nil {
+		s.Meta = &FormSettingsMeta{}
+	}
+	return s.Meta
+}
+
+type settingSetter func(*FormSettings, string) error
+
+func settingBool(set func(*FormSettings, *bool)) settingSetter {
+	return func(s *FormSettings, value string) error {
+		b, err := parseBool(value)
+		if err != nil {
+			return err
+		}
+		set(s, &b)
+		return nil
+	}
+}
+
+func settingString(set func(*FormSettings, string)) settingSetter {
+	return func(s *FormSettings, value string) error {
+		set(s, value)
+		return nil
+	}
+}
+
+var languageCode = regexp.MustCompile(`^[a-z]{2}(-[a-z]{2,4})?$`)
+
+func parseLanguage(value string) (string, error) {
+	lang := strings.ToLower(strings.TrimSpace(value))
+	if !languageCode.MatchString(lang) {
+		return "", fmt.Errorf("expected a language code such as en or es, got: %s", value)
+	}
+	return lang, nil
+}
+
+var settingSetters = map[string]settingSetter{
+	"language": func(s *FormSettings, value string) error {
+		lang, err := parseLanguage(value)
+		if err != nil {
+			return err
+		}
+		s.Language = lang
+		return nil
+	},
+	"progress_bar": func(s *FormSettings, value string) error {
+		if value != "proportion" && value != "percentage" {
+			return fmt.Errorf("expected proportion or percentage, got: %s", value)
+		}
+		s.ProgressBar = value
+		return nil
+	},
+	"show_progress_bar":          settingBool(func(s *FormSettings, b *bool) { s.ShowProgressBar = b }),
+	"is_public":                  settingBool(func(s *FormSettings, b *bool) { s.IsPublic = b }),
+	"hide_navigation":            settingBool(func(s *FormSettings, b *bool) { s.HideNavigation = b }),
+	"show_typeform_branding":     settingBool(func(s *FormSettings, b *bool) { s.ShowTypeformBranding = b }),
+	"show_time_to_complete":      settingBool(func(s *FormSettings, b *bool) { s.ShowTimeToComplete = b }),
+	"show_number_of_submissions": settingBool(func(s *FormSettings, b *bool) { s.ShowNumberOfSubmissions = b }),
+	"show_cookie_consent":        settingBool(func(s *FormSettings, b *bool) { s.ShowCookieConsent = b }),
+	"redirect_after_submit_url":  settingString(func(s *FormSettings, v string) { s.RedirectAfterSubmitUrl = v }),
+	"meta.title":                 settingString(func(s *FormSettings, v string) { s.meta().Title = v }),
+	"meta.description":           settingString(func(s *FormSettings, v string) { s.meta().Description = v }),
+	"meta.allow_indexing":        settingBool(func(s *FormSettings, b *bool) { s.meta().AllowIndexing = b }),
+	"theme":                      settingString(func(s *FormSettings, v string) { s.Theme = v }),
+}
+
+// ParseSettings reads the Settings sheet, a setting and its value per
+// row, such as "progress_bar" and "percentage". Rows with a problem
+// are left out, and returned as a ReportError.
+func ParseSettings(records [][]string) (*FormSettings, error) {
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	settings := &FormSettings{}
+	cols := Columns{"setting": 0, "value": 1}
+	report := ErrorReport{}
+
+	for i, r := range records[1:] {
+		key := normalizeHeader(get(r, 0))
+		value := strings.TrimSpace(get(r, 1))
+
+		if key == "" && value == "" {
+			continue
+		}
+
+		set, ok := settingSetters[key]
+		if !ok {
+			report = append(report, sheetError("Settings", cols, i+2, "setting", fmt.Errorf("unknown setting %s", key)))
+			continue
+		}
+
+		err := set(settings, value)
+		if err != nil {
+			report = append(report, sheetError("Settings", cols, i+2, "value", fmt.Errorf("%s: %w", key, err)))
+		}
+	}
+
+	return settings, report.asError()
+}
+
+// mergeSettings returns the base settings with every setting
+// that is set in the override laid over them. Either may be nil.
+func mergeSettings(base, override *FormSettings) (*FormSettings, error) {
+	if base == nil && override == nil {
+		return nil, nil
+	}
+
+	res := &FormSettings{}
+	for _, s := range []*FormSettings{base, override} {
+		if s == nil {
+			continue
+		}
+
+		// unset settings are omitted, so they leave res as it is
+		b, err := json.Marshal(s)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(b, res)
+		if err != nil {
+			return nil, err
+		}
+
+		if s.Theme != "" {
+			res.Theme = s.Theme
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSettings(t *testing.T) {
+	settings, err := ParseSettings([][]string{
+		{"setting", "value"},
+		{"Language", "ES"},
+		{"progress_bar", "percentage"},
+		{},
+		{"show_progress_bar", "no"},
+		{"meta.allow_indexing", "yes"},
+	})
+	assert.Nil(t, err)
+
+	b, _ := json.Marshal(settings)
+	assert.JSONEq(t, `{"language": "es", "progress_bar": "percentage", "show_progress_bar": false, "meta": {"allow_indexing": true}}`, string(b))
+}
+
+func TestParseSettings_ErrorsOnBadSettings(t *testing.T) {
+	rows := [][]string{
+		{"colour", "blue"},
+		{"progress_bar", "dots"},
+		{"is_public", "maybe"},
+		{"language", "Spanish"},
+	}
+
+	for _, row := range rows {
+		_, err := ParseSettings([][]string{{"setting", "value"}, row})
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "Settings sheet, row 2")
+	}
+}
+
+func TestMergeSettings(t *testing.T) {
+	no := false
+	yes := true
+
+	base := &FormSettings{Language: "en", IsPublic: &yes, Meta: &FormSettingsMeta{Title: "Base", AllowIndexing: &yes}}
+	override := &FormSettings{IsPublic: &no, Meta: &FormSettingsMeta{Description: "Translated"}}
+
+	res, err := mergeSettings(base, override)
+	assert.Nil(t, err)
+	assert.Equal(t, "en", res.Language)
+	assert.Equal(t, false, *res.IsPublic)
+	assert.Equal(t, "Base", res.Meta.Title)
+	assert.Equal(t, "Translated", res.Meta.Description)
+	assert.Equal(t, true, *res.Meta.AllowIndexing)
+
+	// the base is left as it was
+	assert.Equal(t, true, *base.IsPublic)
+
+	res, err = mergeSettings(nil, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, res)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+var spreadsheetUrl = regexp.MustCompile(`^https?://docs\.google\.com/spreadsheets/d/([A-Za-z0-9_-]+)`)
+
+var spreadsheetIdPath = regexp.MustCompile(`^gsheet:([A-Za-z0-9_-]+)$`)
+
+// spreadsheetID returns the id of the Google Sheets spreadsheet at
+// path, if path is the url of a spreadsheet or its id as gsheet:<id>.
+// Anything else is a local file or directory, so a mistyped file name
+// is not taken for an id.
+func spreadsheetID(path string) (string, bool) {
+	path = strings.TrimSpace(path)
+	for _, pattern := range []*regexp.Regexp{spreadsheetUrl, spreadsheetIdPath} {
+		if m := pattern.FindStringSubmatch(path); m != nil {
+			return m[1], true
+		}
+	}
+	return "", false
+}
+
+// googleSheet is a workbook read from Google Sheets. All of its
+// values are read when it is opened.
+type googleSheet struct {
+	title  string
+	sheets []string
+	rows   map[string][][]string
+}
+
+func (w *googleSheet) Title() string {
+	return w.title
+}
+
+func (w *googleSheet) Sheets() []string {
+	return w.sheets
+}
+
+func (w *googleSheet) Rows(sheet string) ([][]string, error) {
+	rows, ok := w.rows[sheet]
+	if !ok {
+		return nil, fmt.Errorf("Could not find sheet %s in spreadsheet %s", sheet, w.title)
+	}
+	return rows, nil
+}
+
+// openGoogleSheet reads the spreadsheet through the Sheets API. Without
+// options, the credentials are the application default credentials,
+// such as the service account in GOOGLE_APPLICATION_CREDENTIALS.
+func openGoogleSheet(id string, opts []option.ClientOption) (*googleSheet, error) {
+	ctx := context.Background()
+
+	if len(opts) == 0 {
+		opts = []option.ClientOption{option.WithScopes(sheets.SpreadsheetsReadonlyScope)}
+	}
+
+	srv, err := sheets.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Could not connect to Google Sheets: %w", err)
+	}
+
+	spreadsheet, err := srv.Spreadsheets.Get(id).Fields("properties.title", "sheets.properties.title").Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("Could not get spreadsheet %s: %w", id, err)
+	}
+
+	w := &googleSheet{spreadsheet.Properties.Title, []string{}, map[string][][]string{}}
+	ranges := []string{}
+	for _, s := range spreadsheet.Sheets {
+		w.sheets = append(w.sheets, s.Properties.Title)
+		ranges = append(ranges, fmt.Sprintf("'%s'", strings.ReplaceAll(s.Properties.Title, "'", "''")))
+	}
+
+	if len(ranges) == 0 {
+		return w, nil
+	}
+
+	values, err := srv.Spreadsheets.Values.BatchGet(id).Ranges(ranges...).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("Could not get the values of spreadsheet %s: %w", id, err)
+	}
+
+	if len(values.ValueRanges) != len(w.sheets) {
+		return nil, fmt.Errorf("Expected the values of %d sheets of spreadsheet %s, got %d", len(w.sheets), id, len(values.ValueRanges))
+	}
+
+	for i, vr := range values.ValueRanges {
+		rows := make([][]string, len(vr.Values))
+		for j, r := range vr.Values {
+			rows[j] = make([]string, len(r))
+			for k, v := range r {
+				rows[j][k] = fmt.Sprint(v)
+			}
+		}
+		w.rows[w.sheets[i]] = rows
+	}
+
+	return w, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/option"
+)
+
+const spreadsheetId = "1BxiMVs0XRA5nFMdKvBdBZjgmUUqptlbs74OgvE2upms"
+
+// fakeSheets serves a spreadsheet like the Sheets API
+func fakeSheets(t *testing.T) []option.ClientOption {
+	ts, _ := testServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v4/spreadsheets/" + spreadsheetId:
+			fmt.Fprintf(w, `{"properties": {"title": "Vaccines"}, "sheets": [
+              {"properties": {"title": "Baseline"}},
+              {"properties": {"title": "Messages"}},
+              {"properties": {"title": "Logic"}}
+            ]}`)
+
+		case "/v4/spreadsheets/" + spreadsheetId + "/values:batchGet":
+			assert.Equal(t, []string{"'Baseline'", "'Messages'", "'Logic'"}, r.URL.Query()["ranges"])
+			fmt.Fprintf(w, `{"valueRanges": [
+              {"range": "Baseline!A1:D4", "values": [
+                ["ref", "type", "question", "options"],
+                ["consent", "multiple_choice", "Consent?", "Yes\nNo"],
+                ["age", "number", "Age?"],
+                ["optout", "thankyou_screen", "Bye"]
+              ]},
+              {"range": "Messages!A1:B2", "values": [["variable", "message"], ["label.buttonHint.default", "Press enter"]]},
+              {"range": "Logic!A1:C2", "values": [["field", "choice", "jump_to"], ["consent", "No", "optout"]]}
+            ]}`)
+
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+			w.WriteHeader(404)
+		}
+	})
+
+	return []option.ClientOption{option.WithEndpoint(ts.URL), option.WithoutAuthentication()}
+}
+
+func TestSpreadsheetID(t *testing.T) {
+	// a bare id could be a mistyped file name
+	_, ok := spreadsheetID(spreadsheetId)
+	assert.False(t, ok)
+
+	id, ok := spreadsheetID("gsheet:" + spreadsheetId)
+	assert.True(t, ok)
+	assert.Equal(t, spreadsheetId, id)
+
+	id, ok = spreadsheetID("https://docs.google.com/spreadsheets/d/" + spreadsheetId + "/edit#gid=0")
+	assert.True(t, ok)
+	assert.Equal(t, spreadsheetId, id)
+
+	_, ok = spreadsheetID("test/Survey Translation Example.xlsx")
+	assert.False(t, ok)
+
+	_, ok = spreadsheetID("survey.xlsx")
+	assert.False(t, ok)
+}
+
+func TestInitialForms_ReadsGoogleSheets(t *testing.T) {
+	survey := NewSurveyFile("workey", "https://docs.google.com/spreadsheets/d/"+spreadsheetId+"/edit")
+	survey.SheetsOptions = fakeSheets(t)
+
+	forms, _, err := survey.InitialForms()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(forms))
+
+	conf := forms["Baseline"]
+	assert.Equal(t, "Vaccines - Baseline", conf.Name)
+	assert.Equal(t, 2, len(conf.Form.Fields))
+	assert.Equal(t, "No", conf.Form.Fields[0].Properties.Choices[1].Label)
+	assert.Contains(t, string(conf.Form.Logic), `"value":"optout"`)
+	assert.Equal(t, "Press enter", conf.MessagesData[1][1])
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+	"google.golang.org/api/option"
+)
+
+// Workbook is where the sheets of a survey are read from: an Excel
+// file, a directory with a CSV file per sheet or a Google spreadsheet.
+type Workbook interface {
+	Sheets() []string
+	Rows(sheet string) ([][]string, error)
+}
+
+type excelWorkbook struct {
+	file *excelize.File
+}
+
+func (w *excelWorkbook) Sheets() []string {
+	return w.file.GetSheetList()
+}
+
+func (w *excelWorkbook) Rows(sheet string) ([][]string, error) {
+	return w.file.GetRows(sheet)
+}
+
+// csvDirectory is a workbook with a file, such as Baseline.csv,
+// for each sheet
+type csvDirectory struct {
+	path   string
+	sheets []string
+
+	// the file of each sheet, as named in the directory
+	files map[string]string
+}
+
+func newCsvDirectory(path string) (*csvDirectory, error) {
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sheets := []string{}
+	names := map[string]string{}
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || strings.ToLower(filepath.Ext(name)) != ".csv" {
+			continue
+		}
+		sheet := strings.TrimSuffix(name, filepath.Ext(name))
+		sheets = append(sheets, sheet)
+		names[sheet] = name
+	}
+
+	return &csvDirectory{path, sheets, names}, nil
+}
+
+func (w *csvDirectory) Sheets() []string {
+	return w.sheets
+}
+
+func (w *csvDirectory) Rows(sheet string) ([][]string, error) {
+	if name, ok := w.files[sheet]; ok {
+		return readCsvFile(filepath.Join(w.path, name))
+	}
+	return nil, fmt.Errorf("Could not find %s.csv in %s", sheet, w.path)
+}
+
+// OpenWorkbook opens the Excel file or the CSV directory at path, or
+// the Google Sheets spreadsheet if path is its url or id. The options are
+// for the Sheets API.
+func OpenWorkbook(path string, sheetsOptions []option.ClientOption) (Workbook, error) {
+	if id, ok := spreadsheetID(path); ok {
+		return openGoogleSheet(id, sheetsOptions)
+	}
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("Could not find the file %s (Google Sheets spreadsheets are read from their url or from gsheet:<id>)", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return newCsvDirectory(path)
+	}
+
+	f, err := excelize.OpenFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &excelWorkbook{f}, nil
+}
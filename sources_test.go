@@ -0,0 +1,78 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeCsvDirectory(t *testing.T, files map[string]string) string {
+	dir := filepath.Join(t.TempDir(), "vaccines")
+	assert.Nil(t, os.Mkdir(dir, 0755))
+
+	for name, content := range files {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		assert.Nil(t, err)
+	}
+	return dir
+}
+
+func TestInitialForms_ReadsCsvDirectory(t *testing.T) {
+	dir := writeCsvDirectory(t, map[string]string{
+		"Baseline.csv": "\ufeffref,type,question,options\n" +
+			"consent,multiple_choice,Consent?,\"Yes\nNo\"\n" +
+			"name,short_text,Name?\n" +
+			"optout,thankyou_screen,Bye\n",
+		"Endline.csv":  "ref,type,question\nbye,statement,Bye\n",
+		"Messages.csv": "variable,message\nlabel.buttonHint.default,Press enter\n",
+		"Logic.csv":    "form,field,choice,jump_to\nBaseline,consent,No,optout\n",
+		"notes.txt":    "not a sheet",
+	})
+
+	survey := NewSurveyFile("workey", dir)
+	assert.Equal(t, "vaccines", survey.BaseName)
+
+	forms, _, err := survey.InitialForms()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(forms))
+
+	conf := forms["Baseline"]
+	assert.Equal(t, "vaccines - Baseline", conf.Name)
+	assert.Equal(t, dir, conf.Dir)
+	assert.Equal(t, 2, len(conf.Form.Fields))
+	assert.Equal(t, "No", conf.Form.Fields[0].Properties.Choices[1].Label)
+	assert.Contains(t, string(conf.Form.Logic), `"value":"optout"`)
+	assert.Equal(t, "Press enter", conf.MessagesData[1][1])
+
+	assert.Equal(t, 1, len(forms["Endline"].Form.Fields))
+}
+
+func TestInitialForms_ReadsCsvFilesWithUpperCaseExtension(t *testing.T) {
+	dir := writeCsvDirectory(t, map[string]string{
+		"Baseline.CSV": "ref,type,question\nname,short_text,Name?\n",
+		"Messages.csv": "variable,message\n",
+	})
+
+	forms, _, err := NewSurveyFile("workey", dir).InitialForms()
+	assert.Nil(t, err)
+	assert.Equal(t, "name", forms["Baseline"].Form.Fields[0].Ref)
+}
+
+func TestInitialForms_NeedsMessagesCsv(t *testing.T) {
+	dir := writeCsvDirectory(t, map[string]string{
+		"Baseline.csv": "ref,type,question\nname,short_text,Name?\n",
+	})
+
+	_, _, err := NewSurveyFile("workey", dir).InitialForms()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Messages.csv")
+}
+
+func TestOpenWorkbook_ReportsMissingFiles(t *testing.T) {
+	_, err := OpenWorkbook("1BxiMVs0XRA5nFMdKvBdBZjgmUUqptlbs74OgvE2upmz", nil)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Could not find the file 1BxiMVs0XRA5nFMdKvBdBZjgmUUqptlbs74OgvE2upmz")
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+type ThemeColors struct {
+	Question   string `json:"question,omitempty"`
+	Answer     string `json:"answer,omitempty"`
+	Button     string `json:"button,omitempty"`
+	Background string `json:"background,omitempty"`
+}
+
+type ThemeBackground struct {
+	Href       string  `json:"href,omitempty"`
+	Layout     string  `json:"layout,omitempty"`
+	Brightness float64 `json:"brightness,omitempty"`
+}
+
+type ThemeText struct {
+	Alignment string `json:"alignment,omitempty"`
+	FontSize  string `json:"font_size,omitempty"`
+}
+
+type Theme struct {
+	ID                   string           `json:"id,omitempty"`
+	Name                 string           `json:"name"`
+	Font                 string           `json:"font,omitempty"`
+	Colors               *ThemeColors     `json:"colors,omitempty"`
+	Background           *ThemeBackground `json:"background,omitempty"`
+	HasTransparentButton *bool            `json:"has_transparent_button,omitempty"`
+	Fields               *ThemeText       `json:"fields,omitempty"`
+	Screens              *ThemeText       `json:"screens,omitempty"`
+	RoundedCorners       string           `json:"rounded_corners,omitempty"`
+}
+
+// FormTheme points a form to its theme
+type FormTheme struct {
+	Href string `json:"href"`
+}
+
+func themeHref(id string) string {
+	return fmt.Sprintf("https://api.typeform.com/themes/%s", id)
+}
+
+type ThemesResponse struct {
+	TotalItems int      `json:"total_items"`
+	Items      []*Theme `json:"items"`
+}
+
+func (t *TypeformUploader) GetThemes() ([]*Theme, error) {
+	api := t.Api()
+
+	apiError := new(TypeformError)
+	themes := new(ThemesResponse)
+
+	params := struct {
+		PageSize int `url:"page_size"`
+	}{200}
+
+	_, err := api.New().Path("themes").QueryStruct(params).Receive(themes, apiError)
+	if err != nil {
+		return nil, err
+	}
+
+	if !apiError.Empty() {
+		return nil, apiError
+	}
+
+	if themes.TotalItems > len(themes.Items) {
+		return nil, fmt.Errorf("Oops, cannot get all themes. Page size is %d and the total items is %d", len(themes.Items), themes.TotalItems)
+	}
+	return themes.Items, nil
+}
+
+// FindTheme finds a theme by its id or, failing that, by its name
+func (t *TypeformUploader) FindTheme(idOrName string) (*Theme, error) {
+	themes, err := t.GetThemes()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, theme := range themes {
+		if theme.ID == idOrName {
+			return theme, nil
+		}
+	}
+
+	found := []*Theme{}
+	for _, theme := range themes {
+		if strings.EqualFold(theme.Name, idOrName) {
+			found = append(found, theme)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("Could not find theme with id or name: %s", idOrName)
+	case 1:
+		return found[0], nil
+	}
+	return nil, fmt.Errorf("There is more than one theme called %s, use its id instead", idOrName)
+}
+
+// ApplyTheme points the form to the theme named in its settings, if any
+func (t *TypeformUploader) ApplyTheme(form *Form) error {
+	if form.Settings == nil || form.Settings.Theme == "" {
+		return nil
+	}
+
+	theme, err := t.FindTheme(form.Settings.Theme)
+	if err != nil {
+		return err
+	}
+
+	form.Theme = &FormTheme{themeHref(theme.ID)}
+	return nil
+}
+
+func (t *TypeformUploader) sendTheme(theme *Theme) (*Theme, error) {
+	api := t.Api()
+
+	apiError := new(TypeformError)
+	res := new(Theme)
+
+	req := api.New().Post("themes")
+	if theme.ID != "" {
+		req = api.New().Put(fmt.Sprintf("themes/%s", theme.ID))
+	}
+
+	_, err := req.BodyJSON(theme).Receive(res, apiError)
+	if err != nil {
+		return nil, err
+	}
+
+	if !apiError.Empty() {
+		return nil, apiError
+	}
+	return res, nil
+}
+
+// SaveTheme creates the theme in a JSON or YAML file, or updates the
+// theme with the same name. A local background image (relative to the
+// file) is uploaded first.
+func (t *TypeformUploader) SaveTheme(path string) (*Theme, error) {
+	theme := new(Theme)
+	err := readDefinition(path, theme)
+	if err != nil {
+		return nil, err
+	}
+
+	if theme.Name == "" {
+		return nil, fmt.Errorf("The theme in %s has no name", path)
+	}
+
+	if b := theme.Background; b != nil && b.Href != "" && !isTypeformImage(b.Href) {
+		b.Href, err = t.UploadImage(filepath.Dir(path), b.Href)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	themes, err := t.GetThemes()
+	if err != nil {
+		return nil, err
+	}
+
+	theme.ID = ""
+	for _, existing := range themes {
+		if strings.EqualFold(existing.Name, theme.Name) {
+			theme.ID = existing.ID
+		}
+	}
+
+	return t.sendTheme(theme)
+}
+
+// useTheme sets the theme of the forms that don't name one themselves
+func useTheme(formConfs map[string]*FormConf, theme string) {
+	if theme == "" {
+		return
+	}
+
+	for _, c := range formConfs {
+		if c.Form.Settings == nil {
+			c.Form.Settings = &FormSettings{}
+		}
+		if c.Form.Settings.Theme == "" {
+			c.Form.Settings.Theme = theme
+		}
+	}
+}
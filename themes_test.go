@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const themesResponse = `{"total_items": 3, "items": [
+  {"id": "qHWOQ7", "name": "Vlab"},
+  {"id": "a1b2c3", "name": "Plain"},
+  {"id": "d4e5f6", "name": "plain"}
+]}`
+
+func themeUploader(t *testing.T, handler func(http.ResponseWriter, *http.Request)) TypeformUploader {
+	ts, _ := testServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/themes" && r.Method == "GET" {
+			assert.Equal(t, "200", r.URL.Query().Get("page_size"))
+			fmt.Fprintf(w, themesResponse)
+			return
+		}
+		handler(w, r)
+	})
+
+	return TypeformUploader{BaseUrl: ts.URL, TypeformToken: "secret"}
+}
+
+func TestFindTheme_ByIdOrName(t *testing.T) {
+	uploader := themeUploader(t, nil)
+
+	theme, err := uploader.FindTheme("qHWOQ7")
+	assert.Nil(t, err)
+	assert.Equal(t, "Vlab", theme.Name)
+
+	theme, err = uploader.FindTheme("vlab")
+	assert.Nil(t, err)
+	assert.Equal(t, "qHWOQ7", theme.ID)
+
+	_, err = uploader.FindTheme("plain")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "more than one")
+
+	_, err = uploader.FindTheme("fancy")
+	assert.NotNil(t, err)
+}
+
+func TestCreateForm_AppliesThemeFromSettings(t *testing.T) {
+	sent := new(Form)
+
+	uploader := themeUploader(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/forms" && r.Method == "GET":
+			fmt.Fprintf(w, `{"items": []}`)
+
+		case r.URL.Path == "/forms" && r.Method == "POST":
+			json.NewDecoder(r.Body).Decode(sent)
+			w.Header().Set("Location", "https://api.typeform.com/forms/foo")
+			w.WriteHeader(201)
+			fmt.Fprintf(w, `{"id": "foo"}`)
+
+		default:
+			w.WriteHeader(204)
+		}
+	})
+
+	formData := [][]string{
+		{"ref", "type", "question"},
+		{"name", "short_text", "Name?"},
+	}
+	conf, _ := NewFormConf("workspace", "form name", formData, [][]string{{"variable", "message"}}, nil)
+	useTheme(map[string]*FormConf{"Sheet1": conf}, "Vlab")
+
+	err := uploader.CreateForm(conf)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://api.typeform.com/themes/qHWOQ7", sent.Theme.Href)
+}
+
+func TestSaveTheme_UpdatesThemeWithTheSameName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "theme.yaml")
+	ioutil.WriteFile(path, []byte(`
+name: Vlab
+font: Karla
+colors:
+  question: "#3D3D3D"
+  button: "#4FB0AE"
+has_transparent_button: false
+`), 0644)
+
+	sent := new(Theme)
+	uploader := themeUploader(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "PUT", r.Method)
+		assert.Equal(t, "/themes/qHWOQ7", r.URL.Path)
+
+		json.NewDecoder(r.Body).Decode(sent)
+		fmt.Fprintf(w, `{"id": "qHWOQ7", "name": "Vlab"}`)
+	})
+
+	theme, err := uploader.SaveTheme(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "qHWOQ7", theme.ID)
+
+	assert.Equal(t, "Karla", sent.Font)
+	assert.Equal(t, "#4FB0AE", sent.Colors.Button)
+	assert.Equal(t, false, *sent.HasTransparentButton)
+}
+
+func TestSaveTheme_MatchesNamesLikeFindTheme(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "theme.yaml")
+	ioutil.WriteFile(path, []byte("name: VLAB\nfont: Karla\n"), 0644)
+
+	uploader := themeUploader(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "PUT", r.Method)
+		assert.Equal(t, "/themes/qHWOQ7", r.URL.Path)
+		fmt.Fprintf(w, `{"id": "qHWOQ7", "name": "VLAB"}`)
+	})
+
+	theme, err := uploader.SaveTheme(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "qHWOQ7", theme.ID)
+}
+
+func TestSaveTheme_CreatesNewTheme(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "theme.json")
+	ioutil.WriteFile(path, []byte(`{"name": "Fancy", "font": "Arial"}`), 0644)
+
+	uploader := themeUploader(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/themes", r.URL.Path)
+		fmt.Fprintf(w, `{"id": "newone", "name": "Fancy"}`)
+	})
+
+	theme, err := uploader.SaveTheme(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "newone", theme.ID)
+}
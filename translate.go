@@ -2,12 +2,11 @@ package main
 
 import (
 	"fmt"
-
-	"github.com/vlab-research/trans"
 )
 
-func findField(ref string, form *Form) (*trans.Field, error) {
-	for _, f := range form.Fields {
+// findField finds a field by ref, including fields nested in groups
+func findField(ref string, form *Form) (*Field, error) {
+	for _, f := range flattenFields(form.Fields) {
 		if f.Ref == ref {
 			return f, nil
 		}
@@ -15,37 +14,120 @@ func findField(ref string, form *Form) (*trans.Field, error) {
 	return nil, fmt.Errorf("Could not find field ref %v in form titled %v", ref, form.Title)
 }
 
-func copyChoiceRefs(f *trans.Field, src *Form) (*trans.Field, error) {
+// pairChoices finds the choice in the source of each choice of the
+// field: the one with the same ref or, for choices without a ref, the
+// one in the same position. Choices with a ref that is not in the
+// source are new, and have no pair, unless no ref of the field is in
+// the source: then every choice is paired by position.
+func pairChoices(f *Field, srcField *Field) ([]*FieldChoice, error) {
+	choices, srcChoices := f.Properties.Choices, srcField.Properties.Choices
+	pairs := make([]*FieldChoice, len(choices))
+
+	byRef := map[string]*FieldChoice{}
+	for _, c := range srcChoices {
+		if c.Ref != "" {
+			byRef[c.Ref] = c
+		}
+	}
+
+	taken := map[*FieldChoice]bool{}
+	positional := false
+	for j, c := range choices {
+		if c.Ref == "" {
+			positional = true
+			continue
+		}
+		if srcChoice, ok := byRef[c.Ref]; ok {
+			pairs[j] = srcChoice
+			taken[srcChoice] = true
+		}
+	}
+
+	// refs that have nothing to do with the source's
+	if len(taken) == 0 {
+		if len(choices) != len(srcChoices) {
+			return nil, fmt.Errorf("Number of choices not the same for field ref: %v. There are %d choices in the target and %d choices in the source", f.Ref, len(choices), len(srcChoices))
+		}
+		copy(pairs, srcChoices)
+		return pairs, nil
+	}
+
+	if !positional {
+		return pairs, nil
+	}
+
+	if len(choices) != len(srcChoices) {
+		return nil, fmt.Errorf("Number of choices not the same for field ref: %v. There are %d choices in the target and %d choices in the source", f.Ref, len(choices), len(srcChoices))
+	}
+
+	for j, c := range choices {
+		if c.Ref != "" {
+			continue
+		}
+		if taken[srcChoices[j]] {
+			return nil, fmt.Errorf("Choice %d of field ref %v has no ref, and the choice in its position in the source has the ref of another choice (%s)", j+1, f.Ref, srcChoices[j].Ref)
+		}
+		pairs[j] = srcChoices[j]
+	}
+
+	return pairs, nil
+}
+
+func copyChoiceRefs(f *Field, src *Form) (*Field, error) {
 	srcField, err := findField(f.Ref, src)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if len(f.Properties.Choices) != len(srcField.Properties.Choices) {
-		return nil, fmt.Errorf("Number of choices not the same for field ref: %v. There are %d choices in the target and %d choices in the source", f.Ref, len(f.Properties.Choices), len(srcField.Properties.Choices))
+	pairs, err := pairChoices(f, srcField)
+	if err != nil {
+		return nil, err
+	}
+
+	for j, c := range f.Properties.Choices {
+		srcChoice := pairs[j]
+		if srcChoice == nil {
+			continue
+		}
+		c.Ref = srcChoice.Ref
+
+		// translations can reuse the images of the source
+		if c.Attachment == nil {
+			c.Attachment = srcChoice.Attachment
+		}
 	}
 
-	for j := range f.Properties.Choices {
-		f.Properties.Choices[j].Ref = srcField.Properties.Choices[j].Ref
+	// choice flags not set in the sheet are kept from the source
+	flags, srcFlags := f.Properties.choiceFlags(), srcField.Properties.choiceFlags()
+	for _, name := range choiceFlagNames {
+		if *flags[name] == nil {
+			*flags[name] = *srcFlags[name]
+		}
 	}
 
 	return f, nil
 }
 
-func CopyChoiceRefs(src *Form, dest *Form, skipErrors bool) ([]*trans.Field, error) {
-	fields := make([]*trans.Field, len(dest.Fields))
+func copyAllChoiceRefs(src *Form, destFields []*Field, skipErrors bool) ([]*Field, error) {
+	fields := make([]*Field, len(destFields))
 
-	for i, f := range dest.Fields {
+	for i, f := range destFields {
 		field, err := copyChoiceRefs(f, src)
 
 		if (err != nil) && (skipErrors) {
-			fields[i] = f
-			continue
+			field = f
+		} else if err != nil {
+			return nil, err
 		}
 
-		if err != nil {
-			return nil, err
+		// recurse into the questions of a group
+		if field.Properties != nil && len(field.Properties.Fields) > 0 {
+			children, err := copyAllChoiceRefs(src, field.Properties.Fields, skipErrors)
+			if err != nil {
+				return nil, err
+			}
+			field.Properties.Fields = children
 		}
 
 		fields[i] = field
@@ -53,8 +135,12 @@ func CopyChoiceRefs(src *Form, dest *Form, skipErrors bool) ([]*trans.Field, err
 	return fields, nil
 }
 
+func CopyChoiceRefs(src *Form, dest *Form, skipErrors bool) ([]*Field, error) {
+	return copyAllChoiceRefs(src, dest.Fields, skipErrors)
+}
+
 func CheckFields(src *Form, dest *Form) error {
-	for _, f := range src.Fields {
+	for _, f := range flattenFields(src.Fields) {
 		destField, err := findField(f.Ref, dest)
 		if err != nil {
 			return err
@@ -64,11 +150,109 @@ func CheckFields(src *Form, dest *Form) error {
 			return fmt.Errorf("Number of choices not the same for field ref: %v. There are %d choices in the source and %d choices in the target", f.Ref, len(f.Properties.Choices), len(destField.Properties.Choices))
 		}
 
+		for _, c := range f.Properties.Choices {
+			if c.Ref == "" {
+				continue
+			}
+
+			found := false
+			for _, d := range destField.Properties.Choices {
+				found = found || d.Ref == c.Ref
+			}
+			if !found {
+				return fmt.Errorf("The choice %s (ref %s) of field ref %v is not in the target", c.Label, c.Ref, f.Ref)
+			}
+		}
+
+		flags, destFlags := f.Properties.choiceFlags(), destField.Properties.choiceFlags()
+		for _, name := range choiceFlagNames {
+			a, b := isSet(*flags[name]), isSet(*destFlags[name])
+			if a != b {
+				return fmt.Errorf("The %s property is not the same for field ref: %v. It is %t in the source and %t in the target", name, f.Ref, a, b)
+			}
+		}
+
 	}
 
 	return nil
 }
 
+// translateWelcomeScreens replaces each welcome screen of the source
+// with the screen with the same ref in the translation.
+func translateWelcomeScreens(src *Form, translated *Form) ([]*WelcomeScreen, error) {
+	screens := make([]*WelcomeScreen, len(src.WelcomeScreens))
+
+	for i, s := range src.WelcomeScreens {
+		var found *WelcomeScreen
+		for _, t := range translated.WelcomeScreens {
+			if t.Ref == s.Ref {
+				found = t
+			}
+		}
+
+		if found == nil {
+			return nil, fmt.Errorf("Could not find welcome screen ref %v in form titled %v", s.Ref, translated.Title)
+		}
+
+		// keep the image of the source unless the translation has its own
+		if found.Attachment == nil {
+			found.Attachment = s.Attachment
+		}
+
+		screens[i] = found
+	}
+
+	return screens, nil
+}
+
+// translateThankyouScreens keeps the translated title and button text
+// of each thankyou screen, but takes the button behaviour and redirect
+// from the screen with the same ref in the source.
+func translateThankyouScreens(src *Form, translated *Form) []*ThankyouScreen {
+	for _, t := range translated.ThankYouScreens {
+		for _, s := range src.ThankYouScreens {
+			if s.Ref != t.Ref || s.Properties == nil {
+				continue
+			}
+
+			props := *s.Properties
+			if t.Properties != nil && t.Properties.ButtonText != "" {
+				props.ButtonText = t.Properties.ButtonText
+			}
+			t.Properties = &props
+		}
+	}
+
+	return translated.ThankYouScreens
+}
+
+// CopyAttachments gives every field and thankyou screen in dest
+// that has no attachment the attachment (and layout) of the one
+// with the same ref in src.
+func CopyAttachments(src *Form, dest *Form) {
+	for _, f := range flattenFields(dest.Fields) {
+		if f.Attachment != nil {
+			continue
+		}
+
+		srcField, err := findField(f.Ref, src)
+		if err != nil {
+			continue
+		}
+
+		f.Attachment = srcField.Attachment
+		f.Layout = srcField.Layout
+	}
+
+	for _, t := range dest.ThankYouScreens {
+		for _, s := range src.ThankYouScreens {
+			if s.Ref == t.Ref && t.Attachment == nil {
+				t.Attachment = s.Attachment
+			}
+		}
+	}
+}
+
 func TranslateForm(src *Form, translated *Form) (*Form, error) {
 	// Note: mutates translated
 
@@ -77,11 +261,26 @@ func TranslateForm(src *Form, translated *Form) (*Form, error) {
 	// Keep logic and hidden fields from source
 	res.Logic = src.Logic
 	res.Hidden = src.Hidden
+	res.Variables = src.Variables
+
+	// Keep the settings of the source, unless the translation sets them
+	settings, err := mergeSettings(src.Settings, translated.Settings)
+	if err != nil {
+		return nil, err
+	}
+	res.Settings = settings
+	res.Theme = src.Theme
 
-	// translate workspace/title/thanksyouscreens directly
+	// translate workspace/title directly
 	res.Workspace = translated.Workspace
 	res.Title = translated.Title
-	res.ThankYouScreens = translated.ThankYouScreens
+	res.ThankYouScreens = translateThankyouScreens(src, translated)
+
+	welcomeScreens, err := translateWelcomeScreens(src, translated)
+	if err != nil {
+		return nil, err
+	}
+	res.WelcomeScreens = welcomeScreens
 
 	// copy choice refs from source to translation
 	formattedTranslated, err := CopyChoiceRefs(src, translated, true)
@@ -89,6 +288,7 @@ func TranslateForm(src *Form, translated *Form) (*Form, error) {
 		return nil, err
 	}
 	translated.Fields = formattedTranslated
+	CopyAttachments(src, translated)
 
 	// Check to make sure fields and choices look the same
 	// in both forms
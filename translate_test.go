@@ -84,6 +84,7 @@ func TestCopyChoiceRefs_IgnoresMissingFieldsIfSkipErrorsTrue(t *testing.T) {
 	assert.Nil(t, err)
 
 	assert.NotEqual(t, res[0].Properties.Choices, ogForm.Fields[0].Properties.Choices)
+	assert.Equal(t, "ref1", res[0].Properties.Choices[0].Ref)
 
 	// maintains original refs in second field
 	assert.Equal(t, res[1], ogForm.Fields[1])
@@ -128,3 +129,189 @@ func TestTranslateForm_WithPerfectOrder(t *testing.T) {
 
 	assert.Equal(t, "Gracias por su tiempo!", res.ThankYouScreens[0].Title)
 }
+
+func TestTranslateForm_TranslatesQuestionsInGroups(t *testing.T) {
+	j := `{"title":"form name","fields":[{"type":"group","title":"About you","ref":"about","properties":{"fields":[{"type":"multiple_choice","title":"Yes?","ref":"var1","properties":{"choices":[{"label":"yes","ref":"ref1"},{"label":"no","ref":"ref2"}]}}]}}]}`
+
+	jt := `{"title":"form name","fields":[{"type":"group","title":"Sobre ti","ref":"about","properties":{"fields":[{"type":"multiple_choice","title":"Si?","ref":"var1","properties":{"choices":[{"label":"si"},{"label":"no"}]}}]}}]}`
+
+	f := mockForm(j)
+	ft := mockForm(jt)
+
+	res, err := TranslateForm(f, ft)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "Sobre ti", res.Fields[0].Title)
+	child := res.Fields[0].Properties.Fields[0]
+	assert.Equal(t, "Si?", child.Title)
+	assert.Equal(t, "ref1", child.Properties.Choices[0].Ref)
+	assert.Equal(t, "ref2", child.Properties.Choices[1].Ref)
+}
+
+func TestTranslateForm_RaisesIfTranslationMissesGroupedFields(t *testing.T) {
+	j := `{"title":"form name","fields":[{"type":"group","title":"About you","ref":"about","properties":{"fields":[{"type":"short_text","title":"Name?","ref":"var1","properties":{}}]}}]}`
+
+	jt := `{"title":"form name","fields":[{"type":"group","title":"Sobre ti","ref":"about","properties":{"fields":[]}}]}`
+
+	_, err := TranslateForm(mockForm(j), mockForm(jt))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Could not find field ref var1")
+}
+
+func TestTranslateForm_TranslatesMatrixRowsAndScale(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "options", "group"})
+
+	src, _, _ := BuildForm("en", cols, nil, [][]string{
+		{"agree", "matrix", "Do you agree?", "Agree\nDisagree"},
+		{"safe", "multiple_choice", "Vaccines are safe", "", "agree"},
+		{"useful", "multiple_choice", "Vaccines are useful", "", "agree"},
+	})
+
+	for i, row := range src.Fields[0].Properties.Fields {
+		for j, c := range row.Properties.Choices {
+			c.Ref = fmt.Sprintf("ref-%d-%d", i, j)
+		}
+	}
+
+	translated, _, _ := BuildForm("es", cols, nil, [][]string{
+		{"agree", "matrix", "Estas de acuerdo?", "De acuerdo\nEn desacuerdo"},
+		{"safe", "multiple_choice", "Las vacunas son seguras", "", "agree"},
+		{"useful", "multiple_choice", "Las vacunas son utiles", "", "agree"},
+	})
+
+	res, err := TranslateForm(src, translated)
+	assert.Nil(t, err)
+
+	matrix := res.Fields[0]
+	assert.Equal(t, "Estas de acuerdo?", matrix.Title)
+	assert.Equal(t, "Las vacunas son utiles", matrix.Properties.Fields[1].Title)
+	assert.Equal(t, "En desacuerdo", matrix.Properties.Fields[1].Properties.Choices[1].Label)
+	assert.Equal(t, "ref-1-1", matrix.Properties.Fields[1].Properties.Choices[1].Ref)
+	assert.Equal(t, "ref-0-0", matrix.Properties.Fields[0].Properties.Choices[0].Ref)
+}
+
+func TestTranslateForm_TranslatesWelcomeScreensByRef(t *testing.T) {
+	j := `{"title":"form name","fields":[],"welcome_screens":[{"ref":"consent","title":"Welcome","properties":{"show_button":true,"button_text":"Start"},"attachment":{"type":"image","href":"https://images.typeform.com/images/foo"}}]}`
+
+	jt := `{"title":"form name","fields":[],"welcome_screens":[{"ref":"other","title":"Otro"},{"ref":"consent","title":"Bienvenido","properties":{"show_button":true,"button_text":"Empezar"}}]}`
+
+	res, err := TranslateForm(mockForm(j), mockForm(jt))
+	assert.Nil(t, err)
+
+	assert.Equal(t, 1, len(res.WelcomeScreens))
+	assert.Equal(t, "Bienvenido", res.WelcomeScreens[0].Title)
+	assert.Equal(t, "Empezar", res.WelcomeScreens[0].Properties.ButtonText)
+	assert.Equal(t, "https://images.typeform.com/images/foo", res.WelcomeScreens[0].Attachment.Href)
+}
+
+func TestTranslateForm_RaisesIfTranslationMissesWelcomeScreen(t *testing.T) {
+	j := `{"title":"form name","fields":[],"welcome_screens":[{"ref":"consent","title":"Welcome"}]}`
+	jt := `{"title":"form name","fields":[]}`
+
+	_, err := TranslateForm(mockForm(j), mockForm(jt))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "consent")
+}
+
+func TestTranslateForm_TranslatesThankyouButtonTextAndKeepsRedirect(t *testing.T) {
+	j := `{"title":"form name","fields":[],"thankyou_screens":[{"ref":"bye","title":"Bye","properties":{"show_button":true,"button_mode":"redirect","button_text":"Go","redirect_url":"https://example.com"},"attachment":{"type":"image","href":"https://images.typeform.com/images/foo"}}]}`
+
+	jt := `{"title":"form name","fields":[],"thankyou_screens":[{"ref":"bye","title":"Adios","properties":{"show_button":true,"button_text":"Vamos"}}]}`
+
+	res, err := TranslateForm(mockForm(j), mockForm(jt))
+	assert.Nil(t, err)
+
+	ty := res.ThankYouScreens[0]
+	assert.Equal(t, "Adios", ty.Title)
+	assert.Equal(t, "Vamos", ty.Properties.ButtonText)
+	assert.Equal(t, "redirect", ty.Properties.ButtonMode)
+	assert.Equal(t, "https://example.com", ty.Properties.RedirectUrl)
+	assert.Equal(t, "https://images.typeform.com/images/foo", ty.Attachment.Href)
+}
+
+func TestTranslateForm_KeepsChoiceFlagsFromSourceWhenNotSet(t *testing.T) {
+	j := `{"title":"form name","fields":[{"type":"multiple_choice","title":"hello","ref":"var1","properties":{"vertical_alignment":true,"allow_multiple_selection":true,"choices":[{"label":"A"},{"label":"B"}]}}]}`
+
+	jt := `{"title":"form name","fields":[{"type":"multiple_choice","title":"hola","ref":"var1","properties":{"allow_multiple_selection":true,"choices":[{"label":"C"},{"label":"D"}]}}]}`
+
+	res, err := TranslateForm(mockForm(j), mockForm(jt))
+	assert.Nil(t, err)
+	assert.Equal(t, true, *res.Fields[0].Properties.VerticalAlignment)
+	assert.Equal(t, true, *res.Fields[0].Properties.AllowMultipleSelection)
+	assert.Nil(t, res.Fields[0].Properties.Randomize)
+}
+
+func TestTranslateForm_RaisesIfChoiceFlagsDiffer(t *testing.T) {
+	j := `{"title":"form name","fields":[{"type":"multiple_choice","title":"hello","ref":"var1","properties":{"allow_multiple_selection":true,"choices":[{"label":"A"},{"label":"B"}]}}]}`
+
+	jt := `{"title":"form name","fields":[{"type":"multiple_choice","title":"hola","ref":"var1","properties":{"allow_multiple_selection":false,"choices":[{"label":"C"},{"label":"D"}]}}]}`
+
+	_, err := TranslateForm(mockForm(j), mockForm(jt))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "allow_multiple_selection")
+}
+
+func TestCopyAttachments_KeepsAttachmentsMissingInDest(t *testing.T) {
+	f := readFile("logic_test_en.json")
+	dest := mockForm(`{"title":"form name","fields":[{"type":"multiple_choice","title":"Pick","ref":"pick_road","properties":{}}],"thankyou_screens":[{"ref":"default_tys","title":"Bye"}]}`)
+
+	CopyAttachments(f, dest)
+
+	assert.Equal(t, "https://images.typeform.com/images/WMALzu59xbXQ", dest.Fields[0].Attachment.Href)
+	assert.Equal(t, "split", dest.Fields[0].Layout.Type)
+	assert.Equal(t, "https://images.typeform.com/images/2dpnUBBkz2VN", dest.ThankYouScreens[0].Attachment.Href)
+}
+
+func TestCopyChoiceRefs_MatchesChoicesByRefFirst(t *testing.T) {
+	src := mockForm(`{"title":"form name","fields":[{"type":"multiple_choice","title":"hello","ref":"var1","properties":{"choices":[{"label":"Yes","ref":"yes","attachment":{"type":"image","href":"https://images.typeform.com/yes"}},{"label":"No","ref":"no"},{"label":"Maybe","ref":"maybe"}]}}]}`)
+
+	// reordered, with a new choice and without maybe
+	dest := mockForm(`{"title":"form name","fields":[{"type":"multiple_choice","title":"hello","ref":"var1","properties":{"choices":[{"label":"No","ref":"no"},{"label":"Don't know","ref":"dunno"},{"label":"Yes","ref":"yes"}]}}]}`)
+
+	res, err := CopyChoiceRefs(src, dest, false)
+	assert.Nil(t, err)
+
+	choices := res[0].Properties.Choices
+	assert.Equal(t, "no", choices[0].Ref)
+	assert.Equal(t, "dunno", choices[1].Ref)
+	assert.Equal(t, "yes", choices[2].Ref)
+
+	// the attachment follows the ref, not the position
+	assert.Nil(t, choices[0].Attachment)
+	assert.Equal(t, "https://images.typeform.com/yes", choices[2].Attachment.Href)
+}
+
+func TestCopyChoiceRefs_FallsBackToPositionWithoutRefs(t *testing.T) {
+	src := mockForm(`{"title":"form name","fields":[{"type":"multiple_choice","title":"hello","ref":"var1","properties":{"choices":[{"label":"Yes","ref":"yes"},{"label":"No","ref":"no"},{"label":"Maybe","ref":"maybe"}]}}]}`)
+
+	dest := mockForm(`{"title":"form name","fields":[{"type":"multiple_choice","title":"hola","ref":"var1","properties":{"choices":[{"label":"Si"},{"label":"Quizas","ref":"maybe"},{"label":"No"}]}}]}`)
+
+	_, err := CopyChoiceRefs(src, dest, false)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Choice 3 of field ref var1")
+
+	dest = mockForm(`{"title":"form name","fields":[{"type":"multiple_choice","title":"hola","ref":"var1","properties":{"choices":[{"label":"Si"},{"label":"No"},{"label":"Quizas","ref":"maybe"}]}}]}`)
+
+	res, err := CopyChoiceRefs(src, dest, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "yes", res[0].Properties.Choices[0].Ref)
+	assert.Equal(t, "no", res[0].Properties.Choices[1].Ref)
+	assert.Equal(t, "maybe", res[0].Properties.Choices[2].Ref)
+}
+
+func TestTranslateForm_MatchesReorderedChoicesByRef(t *testing.T) {
+	src := mockForm(`{"title":"form name","fields":[{"type":"multiple_choice","title":"hello","ref":"var1","properties":{"choices":[{"label":"Yes","ref":"yes"},{"label":"No","ref":"no"}]}}]}`)
+
+	translated := mockForm(`{"title":"form name","fields":[{"type":"multiple_choice","title":"hola","ref":"var1","properties":{"choices":[{"label":"No","ref":"no"},{"label":"Si","ref":"yes"}]}}]}`)
+
+	res, err := TranslateForm(src, translated)
+	assert.Nil(t, err)
+	assert.Equal(t, "Si", res.Fields[0].Properties.Choices[1].Label)
+	assert.Equal(t, "yes", res.Fields[0].Properties.Choices[1].Ref)
+
+	translated = mockForm(`{"title":"form name","fields":[{"type":"multiple_choice","title":"hola","ref":"var1","properties":{"choices":[{"label":"No","ref":"no"},{"label":"Si","ref":"si"}]}}]}`)
+
+	_, err = TranslateForm(src, translated)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "ref yes")
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type FieldValidations struct {
+	Required     bool `json:"required"`
+	MaxLength    int  `json:"max_length,omitempty"`
+	MinValue     *int `json:"min_value,omitempty"`
+	MaxValue     *int `json:"max_value,omitempty"`
+	MinSelection int  `json:"min_selection,omitempty"`
+	MaxSelection int  `json:"max_selection,omitempty"`
+}
+
+func (f *Field) validations() *FieldValidations {
+	if f.Validations == nil {
+		f.Validations = &FieldValidations{}
+	}
+	return f.Validations
+}
+
+var validationSetters = map[string]fieldSetter{
+	"max_length":    intSetter(func(f *Field, v int) { f.validations().MaxLength = v }),
+	"min_value":     intSetter(func(f *Field, v int) { f.validations().MinValue = &v }),
+	"max_value":     intSetter(func(f *Field, v int) { f.validations().MaxValue = &v }),
+	"min_selection": intSetter(func(f *Field, v int) { f.validations().MinSelection = v }),
+	"max_selection": intSetter(func(f *Field, v int) { f.validations().MaxSelection = v }),
+}
+
+// Validations that may be set on each field type from the validations
+// column. Every type here can also be marked required.
+var fieldTypeValidations = map[string][]string{
+	"short_text":      {"max_length"},
+	"long_text":       {"max_length"},
+	"number":          {"min_value", "max_value"},
+	"multiple_choice": {"min_selection", "max_selection"},
+	"picture_choice":  {"min_selection", "max_selection"},
+	"dropdown":        {},
+	"ranking":         {},
+	"yes_no":          {},
+	"email":           {},
+	"website":         {},
+	"legal":           {},
+	"file_upload":     {},
+	"opinion_scale":   {},
+	"nps":             {},
+	"rating":          {},
+	"date":            {},
+	"phone_number":    {},
+	"matrix":          {},
+}
+
+// parseYesNo reads a yes/no column. An empty cell is a no.
+func parseYesNo(column, cell string) (bool, error) {
+	if strings.TrimSpace(cell) == "" {
+		return false, nil
+	}
+
+	b, err := parseBool(cell)
+	if err != nil {
+		return false, fmt.Errorf("The %s column should be yes or no, got: %s", column, cell)
+	}
+	return b, nil
+}
+
+// ParseValidations sets the required flag and the validations in a
+// validations cell, such as "max_length=20", on the field.
+func ParseValidations(f *Field, requiredCell, cell string) error {
+	required, err := parseYesNo("required", requiredCell)
+	if err != nil {
+		return err
+	}
+
+	if required {
+		if _, ok := fieldTypeValidations[f.Type]; !ok {
+			return fmt.Errorf("%s questions cannot be required", f.Type)
+		}
+		f.validations().Required = true
+	}
+
+	err = applyKeyValues("validation", f, cell, validationSetters, fieldTypeValidations)
+	if err != nil {
+		return err
+	}
+
+	v := f.Validations
+	if v == nil {
+		return nil
+	}
+
+	if v.MinValue != nil && v.MaxValue != nil && *v.MinValue > *v.MaxValue {
+		return fmt.Errorf("min_value %d is greater than max_value %d", *v.MinValue, *v.MaxValue)
+	}
+
+	if (v.MinSelection != 0 || v.MaxSelection != 0) && !isSet(f.Properties.AllowMultipleSelection) {
+		return fmt.Errorf("min_selection and max_selection need the allow_multiple_selection property")
+	}
+
+	if v.MinSelection != 0 && v.MaxSelection != 0 && v.MinSelection > v.MaxSelection {
+		return fmt.Errorf("min_selection %d is greater than max_selection %d", v.MinSelection, v.MaxSelection)
+	}
+
+	return nil
+}
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// the survey sheet that XLSForm rows are translated into
+var xlsformColumns = []string{"ref", "type", "question", "options", "description", "required", "properties", "validations", "group", "attachment", "choice_refs"}
+
+// XLSForm types that are the same question in Typeform
+var xlsformTypes = map[string]string{
+	"text":            "short_text",
+	"integer":         "number",
+	"decimal":         "number",
+	"note":            "statement",
+	"date":            "date",
+	"select_one":      "multiple_choice",
+	"select_multiple": "multiple_choice",
+	"rank":            "ranking",
+	"file":            "file_upload",
+	"acknowledge":     "legal",
+}
+
+// XLSForm types that collect metadata, which Typeform forms don't
+var xlsformMetadata = map[string]bool{
+	"start":            true,
+	"end":              true,
+	"today":            true,
+	"deviceid":         true,
+	"subscriberid":     true,
+	"simserial":        true,
+	"phonenumber":      true,
+	"username":         true,
+	"email":            true,
+	"audit":            true,
+	"start-geopoint":   true,
+	"background-audio": true,
+}
+
+var xlsformReference = regexp.MustCompile(`\$\{([^}\s]+)\}`)
+
+// xlsformRecall turns references such as ${name} into Typeform recalls
+func xlsformRecall(text string) string {
+	return xlsformReference.ReplaceAllString(text, "{{field:$1}}")
+}
+
+// xlsformHeader finds the columns of an XLSForm sheet. The label and
+// hint columns are the ones without a language, or else the ones of
+// the language, or else the first ones.
+func xlsformHeader(header []string, language string) Columns {
+	cols := Columns{}
+	for i, h := range header {
+		name := strings.ToLower(strings.TrimSpace(h))
+		if _, ok := cols[name]; !ok {
+			cols[name] = i
+		}
+	}
+
+	for _, name := range []string{"label", "hint", "media::image", "image"} {
+		if _, ok := cols[name]; ok {
+			continue
+		}
+
+		for i, h := range header {
+			parts := strings.SplitN(strings.TrimSpace(h), "::", 2)
+			if len(parts) != 2 || strings.ToLower(parts[0]) != name {
+				continue
+			}
+			if _, ok := cols[name]; !ok || parts[1] == language {
+				cols[name] = i
+			}
+		}
+	}
+
+	return cols
+}
+
+type xlsformChoice struct {
+	name  string
+	label string
+}
+
+type xlsformRelevant struct {
+	ref      string
+	relevant string
+	row      int
+}
+
+type xlsformImport struct {
+	choices  map[string][]xlsformChoice
+	rows     [][]string
+	rowsFrom []int
+	relevant []*xlsformRelevant
+	problems []string
+
+	// the type and choices of each question, by ref
+	types   map[string]string
+	options map[string][]xlsformChoice
+}
+
+func (x *xlsformImport) report(row int, format string, a ...interface{}) {
+	x.problems = append(x.problems, fmt.Sprintf("survey row %d: %s", row, fmt.Sprintf(format, a...)))
+}
+
+func (x *xlsformImport) readChoices(records [][]string, language string) {
+	x.choices = map[string][]xlsformChoice{}
+	if len(records) == 0 {
+		return
+	}
+
+	cols := xlsformHeader(records[0], language)
+	for i, r := range records[1:] {
+		list := strings.TrimSpace(cols.Get(r, "list_name"))
+		name := strings.TrimSpace(cols.Get(r, "name"))
+		label := strings.TrimSpace(cols.Get(r, "label"))
+
+		if list == "" && name == "" {
+			continue
+		}
+
+		if label == "" {
+			label = name
+		}
+
+		if checkChoiceRef(name) != nil {
+			x.problems = append(x.problems, fmt.Sprintf("choices row %d: %s cannot be a choice ref, the choice has no ref", i+2, name))
+			name = ""
+		}
+
+		x.choices[list] = append(x.choices[list], xlsformChoice{name, xlsformRecall(label)})
+	}
+}
+
+// xlsformOptions writes the choices of a list as an options
+// cell and a choice_refs cell
+func xlsformOptions(choices []xlsformChoice) (string, string) {
+	lines := make([]string, len(choices))
+	refs := make([]string, len(choices))
+	for i, c := range choices {
+		lines[i], refs[i] = c.label, c.name
+	}
+	return strings.Join(lines, "\n"), strings.Join(refs, "\n")
+}
+
+var xlsformBound = regexp.MustCompile(`^\.\s*(>=|<=|>|<)\s*(-?\d+)$`)
+
+// xlsformConstraint turns constraints such as ". >= 0 and . <= 120"
+// on integers into validations
+func xlsformConstraint(constraint string) (string, bool) {
+	validations := []string{}
+
+	for _, term := range regexp.MustCompile(`\s+and\s+`).Split(strings.TrimSpace(constraint), -1) {
+		m := xlsformBound.FindStringSubmatch(strings.TrimSpace(term))
+		if m == nil {
+			return "", false
+		}
+
+		n, _ := strconv.Atoi(m[2])
+		switch m[1] {
+		case ">":
+			n++
+			fallthrough
+		case ">=":
+			validations = append(validations, fmt.Sprintf("min_value=%d", n))
+		case "<":
+			n--
+			fallthrough
+		case "<=":
+			validations = append(validations, fmt.Sprintf("max_value=%d", n))
+		}
+	}
+
+	return strings.Join(validations, "; "), true
+}
+
+func (x *xlsformImport) readSurvey(records [][]string, language string) {
+	x.types = map[string]string{}
+	x.options = map[string][]xlsformChoice{}
+
+	if len(records) == 0 {
+		return
+	}
+
+	cols := xlsformHeader(records[0], language)
+	groups := []string{}
+
+	for i, r := range records[1:] {
+		n := i + 2
+		typ := strings.Fields(strings.ToLower(cols.Get(r, "type")))
+		name := strings.TrimSpace(cols.Get(r, "name"))
+		label := xlsformRecall(strings.TrimSpace(cols.Get(r, "label")))
+		hint := xlsformRecall(strings.TrimSpace(cols.Get(r, "hint")))
+
+		if len(typ) == 0 {
+			continue
+		}
+
+		group := ""
+		if len(groups) > 0 {
+			group = groups[len(groups)-1]
+		}
+
+		kind := typ[0]
+		if kind == "begin" || kind == "end" {
+			kind = strings.Join(typ, "_")
+		}
+
+		switch {
+		case kind == "begin_group" || kind == "begin_repeat":
+			if kind == "begin_repeat" {
+				x.report(n, "repeat %s cannot repeat in Typeform, its questions are imported once", name)
+			}
+
+			if group != "" {
+				x.report(n, "group %s is inside of group %s, its questions are put in %s", name, group, group)
+				groups = append(groups, group)
+				continue
+			}
+
+			if label == "" {
+				label = name
+			}
+			x.rows = append(x.rows, []string{name, "group", label, "", hint})
+			x.rowsFrom = append(x.rowsFrom, n)
+			x.types[name] = "group"
+			groups = append(groups, name)
+			x.addRelevant(cols, r, name, n)
+			continue
+
+		case kind == "end_group" || kind == "end_repeat":
+			if len(groups) > 0 {
+				groups = groups[:len(groups)-1]
+			}
+			continue
+
+		case xlsformMetadata[kind]:
+			x.report(n, "%s is metadata, which Typeform does not collect", kind)
+			continue
+		}
+
+		typeformType, ok := xlsformTypes[kind]
+		if !ok {
+			x.report(n, "%s questions (%s) cannot be made in Typeform", kind, name)
+			continue
+		}
+
+		if label == "" {
+			x.report(n, "question %s has no label", name)
+			continue
+		}
+
+		options, choiceRefs := "", ""
+		properties := []string{}
+
+		if kind == "select_one" || kind == "select_multiple" || kind == "rank" {
+			if len(typ) < 2 {
+				x.report(n, "question %s has no list of choices", name)
+				continue
+			}
+
+			choices, ok := x.choices[typ[1]]
+			if !ok {
+				x.report(n, "question %s uses the list %s, which is not in the choices sheet", name, typ[1])
+				continue
+			}
+			options, choiceRefs = xlsformOptions(choices)
+			x.options[name] = choices
+
+			if len(typ) > 2 && typ[2] == "or_other" {
+				properties = append(properties, "allow_other_choice=yes")
+			}
+			if kind == "select_multiple" {
+				properties = append(properties, "allow_multiple_selection=yes")
+			}
+		}
+
+		appearance := strings.TrimSpace(cols.Get(r, "appearance"))
+		switch {
+		case appearance == "":
+		case kind == "text" && appearance == "multiline":
+			typeformType = "long_text"
+		case kind == "select_one" && (appearance == "minimal" || appearance == "autocomplete" || appearance == "search"):
+			typeformType = "dropdown"
+		default:
+			x.report(n, "the appearance %s of question %s is not imported", appearance, name)
+		}
+
+		if group != "" && typeformType == "dropdown" {
+			typeformType = "multiple_choice"
+			x.report(n, "question %s is a multiple_choice instead of a dropdown", name)
+		}
+
+		required := ""
+		switch strings.ToLower(strings.TrimSpace(cols.Get(r, "required"))) {
+		case "", "no", "false", "false()":
+		case "yes", "true", "true()":
+			required = "yes"
+		default:
+			x.report(n, "question %s is required on a condition, which is not imported", name)
+		}
+
+		validations := ""
+		if constraint := strings.TrimSpace(cols.Get(r, "constraint")); constraint != "" {
+			validations, ok = xlsformConstraint(constraint)
+			if !ok || kind != "integer" {
+				validations = ""
+				x.report(n, "the constraint %s of question %s is not imported", constraint, name)
+			}
+		}
+
+		for _, column := range []string{"choice_filter", "default", "calculation", "read_only", "repeat_count"} {
+			if v := strings.TrimSpace(cols.Get(r, column)); v != "" {
+				x.report(n, "the %s of question %s is not imported", column, name)
+			}
+		}
+
+		attachment := strings.TrimSpace(cols.Get(r, "media::image"))
+		if attachment == "" {
+			attachment = strings.TrimSpace(cols.Get(r, "image"))
+		}
+
+		x.rows = append(x.rows, []string{name, typeformType, label, options, hint, required, strings.Join(properties, "; "), validations, group, attachment, choiceRefs})
+		x.rowsFrom = append(x.rowsFrom, n)
+		x.types[name] = typeformType
+		if group == "" {
+			x.addRelevant(cols, r, name, n)
+		} else if strings.TrimSpace(cols.Get(r, "relevant")) != "" {
+			x.report(n, "question %s in group %s only shows on a condition, which is not imported", name, group)
+		}
+	}
+}
+
+func (x *xlsformImport) addRelevant(cols Columns, r []string, name string, row int) {
+	relevant := strings.TrimSpace(cols.Get(r, "relevant"))
+	x.relevant = append(x.relevant, &xlsformRelevant{name, relevant, row})
+}
+
+var xlsformEquals = regexp.MustCompile(`^\$\{([^}\s]+)\}\s*(=|!=)\s*'([^']*)'$`)
+var xlsformSelected = regexp.MustCompile(`^selected\(\s*\$\{([^}\s]+)\}\s*,\s*'([^']*)'\s*\)$`)
+
+// parseRelevant reads relevant expressions on the answer of one
+// question, such as "${consent} = 'yes' or ${consent} = 'maybe'". It
+// returns the question and the choice names that show the question.
+func parseRelevant(relevant string, choices func(string) []xlsformChoice) (string, map[string]bool, bool) {
+	ref := ""
+	shown := map[string]bool{}
+
+	terms := regexp.MustCompile(`\s+or\s+`).Split(strings.TrimSpace(relevant), -1)
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+
+		var r, op, value string
+		if m := xlsformEquals.FindStringSubmatch(term); m != nil {
+			r, op, value = m[1], m[2], m[3]
+		} else if m := xlsformSelected.FindStringSubmatch(term); m != nil {
+			r, op, value = m[1], "=", m[2]
+		} else {
+			return "", nil, false
+		}
+
+		if ref != "" && r != ref {
+			return "", nil, false
+		}
+		ref = r
+
+		if op == "!=" {
+			if len(terms) > 1 {
+				return "", nil, false
+			}
+			for _, c := range choices(ref) {
+				if c.name != value {
+					shown[c.name] = true
+				}
+			}
+			continue
+		}
+		shown[value] = true
+	}
+
+	return ref, shown, true
+}
+
+// logic turns the relevant expressions into jumps. A run of questions
+// with the same expression on the answer of the question right before
+// them is skipped with a jump from that question.
+func (x *xlsformImport) logic() [][]string {
+	logic := [][]string{{"field", "choice", "jump_to"}}
+
+	for i := 0; i < len(x.relevant); i++ {
+		r := x.relevant[i]
+		if r.relevant == "" {
+			continue
+		}
+
+		end := i
+		for end+1 < len(x.relevant) && x.relevant[end+1].relevant == r.relevant {
+			end++
+		}
+
+		ref, shown, ok := parseRelevant(r.relevant, func(ref string) []xlsformChoice { return x.options[ref] })
+		switch {
+		case !ok:
+			x.report(r.row, "the condition %s of %s cannot be made with jumps", r.relevant, r.ref)
+		case i == 0 || x.relevant[i-1].ref != ref:
+			x.report(r.row, "the condition %s of %s is on %s, which is not the question right before it", r.relevant, r.ref, ref)
+		case x.types[ref] != "multiple_choice" && x.types[ref] != "dropdown":
+			x.report(r.row, "the condition %s of %s is on %s, which is not a select_one question", r.relevant, r.ref, ref)
+		case end+1 == len(x.relevant):
+			x.report(r.row, "the condition %s of %s is on the last questions of the form, which cannot be skipped", r.relevant, r.ref)
+		default:
+			for _, c := range x.options[ref] {
+				if !shown[c.name] {
+					logic = append(logic, []string{ref, c.label, x.relevant[end+1].ref})
+				}
+			}
+		}
+
+		i = end
+	}
+
+	return logic
+}
+
+// ImportXLSForm builds a form from an XLSForm workbook, with its survey,
+// choices and settings sheets. Everything that cannot be made in
+// Typeform is left out and reported.
+func ImportXLSForm(workspace, path string) (*FormConf, []string, error) {
+	f, err := OpenWorkbook(path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	records := map[string][][]string{}
+	for _, s := range f.Sheets() {
+		name := strings.ToLower(s)
+		if name != "survey" && name != "choices" && name != "settings" {
+			continue
+		}
+		records[name], err = f.Rows(s)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(records["survey"]) == 0 {
+		return nil, nil, fmt.Errorf("XLSForm %s has no survey sheet", path)
+	}
+
+	base := filepath.Base(path)
+	title := strings.TrimSuffix(base, filepath.Ext(base))
+	language := ""
+
+	if settings := records["settings"]; len(settings) > 1 {
+		cols := xlsformHeader(settings[0], "")
+		if t := strings.TrimSpace(cols.Get(settings[1], "form_title")); t != "" {
+			title = t
+		}
+		language = strings.TrimSpace(cols.Get(settings[1], "default_language"))
+	}
+
+	x := &xlsformImport{}
+	x.readChoices(records["choices"], language)
+	x.readSurvey(records["survey"], language)
+
+	conf, err := NewFormConf(workspace, title, append([][]string{xlsformColumns}, x.rows...), [][]string{{"variable", "message"}}, nil)
+	if err != nil {
+		return nil, x.problems, err
+	}
+
+	// rows left out of the form are reported as rows of the survey sheet
+	for _, e := range conf.Errors {
+		x.report(x.rowsFrom[e.Row-2], "%s", e.Message)
+	}
+	conf.Errors = nil
+
+	conf.Dir = filepath.Dir(path)
+	conf.Sheet = "survey"
+	conf.LogicData = x.logic()
+
+	conf.Form.Logic, err = BuildLogic(conf.Form, conf.Sheet, conf.LogicData)
+	if err != nil {
+		return nil, x.problems, err
+	}
+
+	// languages look like "English (en)"
+	if m := regexp.MustCompile(`\(([A-Za-z-]+)\)\s*$`).FindStringSubmatch(language); m != nil {
+		language = m[1]
+	}
+	if lang, err := languageFromName(language); err == nil {
+		conf.Form.Settings = &FormSettings{Language: lang}
+	}
+
+	return conf, x.problems, nil
+}
@@ -0,0 +1,512 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Typeform questions that are the same question in XLSForm
+var exportTypes = map[string]string{
+	"short_text":   "text",
+	"long_text":    "text",
+	"email":        "text",
+	"website":      "text",
+	"phone_number": "text",
+	"number":       "integer",
+	"statement":    "note",
+	"date":         "date",
+	"ranking":      "rank",
+	"file_upload":  "file",
+	"legal":        "acknowledge",
+}
+
+// languageLabel names a language the way XLSForm columns
+// do, such as "English (en)"
+func languageLabel(code string) string {
+	for name, c := range languageNames {
+		if c == code {
+			return fmt.Sprintf("%s%s (%s)", strings.ToUpper(name[:1]), name[1:], code)
+		}
+	}
+	return code
+}
+
+// the labels of the yes_no list, by language code
+var yesNoLabels = map[string][2]string{
+	"ar": {"نعم", "لا"},
+	"hy": {"Այո", "Ոչ"},
+	"az": {"Bəli", "Xeyr"},
+	"bn": {"হ্যাঁ", "না"},
+	"bg": {"Да", "Не"},
+	"ca": {"Sí", "No"},
+	"zh": {"是", "否"},
+	"hr": {"Da", "Ne"},
+	"cs": {"Ano", "Ne"},
+	"da": {"Ja", "Nej"},
+	"nl": {"Ja", "Nee"},
+	"en": {"Yes", "No"},
+	"et": {"Jah", "Ei"},
+	"fi": {"Kyllä", "Ei"},
+	"fr": {"Oui", "Non"},
+	"ka": {"კი", "არა"},
+	"de": {"Ja", "Nein"},
+	"el": {"Ναι", "Όχι"},
+	"he": {"כן", "לא"},
+	"hi": {"हाँ", "नहीं"},
+	"hu": {"Igen", "Nem"},
+	"id": {"Ya", "Tidak"},
+	"it": {"Sì", "No"},
+	"ja": {"はい", "いいえ"},
+	"ko": {"예", "아니요"},
+	"lv": {"Jā", "Nē"},
+	"lt": {"Taip", "Ne"},
+	"no": {"Ja", "Nei"},
+	"fa": {"بله", "خیر"},
+	"pl": {"Tak", "Nie"},
+	"pt": {"Sim", "Não"},
+	"ro": {"Da", "Nu"},
+	"ru": {"Да", "Нет"},
+	"sr": {"Да", "Не"},
+	"sk": {"Áno", "Nie"},
+	"sl": {"Da", "Ne"},
+	"es": {"Sí", "No"},
+	"sw": {"Ndiyo", "Hapana"},
+	"sv": {"Ja", "Nej"},
+	"th": {"ใช่", "ไม่ใช่"},
+	"tr": {"Evet", "Hayır"},
+	"uk": {"Так", "Ні"},
+	"ur": {"ہاں", "نہیں"},
+	"vi": {"Có", "Không"},
+}
+
+var fieldRecall = regexp.MustCompile(`{{\s*field:([^}\s]+)\s*}}`)
+
+type xlsformExport struct {
+	base      *Form
+	languages []string
+	forms     []map[string]*Field
+	screens   []map[string]*WelcomeScreen
+
+	survey   [][]string
+	choices  [][]string
+	lists    map[string]bool
+	problems []string
+}
+
+func (x *xlsformExport) report(format string, a ...interface{}) {
+	x.problems = append(x.problems, fmt.Sprintf(format, a...))
+}
+
+// text turns recalls into XLSForm references, such as ${name}
+func (x *xlsformExport) text(where, text string) string {
+	text = fieldRecall.ReplaceAllString(text, "$${$1}")
+	for _, m := range recallPlaceholder.FindAllString(text, -1) {
+		x.report("%s: the recall %s cannot be exported", where, m)
+	}
+	return text
+}
+
+// labels are the titles and descriptions of a field in every language
+func (x *xlsformExport) labels(f *Field) ([]string, []string) {
+	labels := make([]string, len(x.forms))
+	hints := make([]string, len(x.forms))
+
+	for i, fields := range x.forms {
+		tf, ok := fields[f.Ref]
+		if !ok {
+			if i > 0 {
+				x.report("Question %s has no %s translation", f.Ref, x.languages[i])
+			}
+			continue
+		}
+
+		labels[i] = x.text("Question "+f.Ref, tf.Title)
+		if tf.Properties != nil {
+			hints[i] = x.text("Question "+f.Ref, tf.Properties.Description)
+		}
+	}
+	return labels, hints
+}
+
+// screenLabels are the titles of a welcome screen in every language
+func (x *xlsformExport) screenLabels(s *WelcomeScreen) []string {
+	labels := make([]string, len(x.screens))
+
+	for i, screens := range x.screens {
+		ts, ok := screens[s.Ref]
+		if !ok {
+			if i > 0 {
+				x.report("Welcome screen %s has no %s translation", s.Ref, x.languages[i])
+			}
+			continue
+		}
+
+		labels[i] = x.text("Welcome screen "+s.Ref, ts.Title)
+	}
+	return labels
+}
+
+// choiceName is the ref of the choice, or the ref that
+// jumps on the choice would give it, see choiceRef
+func choiceName(f *Field, i int) string {
+	if c := f.Properties.Choices[i]; c.Ref != "" {
+		return c.Ref
+	}
+	return fmt.Sprintf("%s_%d", f.Ref, i+1)
+}
+
+// addList writes the choices of the field, in every language,
+// to the choices sheet as a list named after the field
+func (x *xlsformExport) addList(f *Field) {
+	translated := make([][]*FieldChoice, len(f.Properties.Choices))
+	for i := range translated {
+		translated[i] = make([]*FieldChoice, len(x.forms))
+		translated[i][0] = f.Properties.Choices[i]
+	}
+
+	for l, fields := range x.forms[1:] {
+		tf, ok := fields[f.Ref]
+		if !ok || tf.Properties == nil {
+			continue
+		}
+
+		pairs, err := pairChoices(tf, f)
+		if err != nil {
+			x.report("Question %s in %s: %s", f.Ref, x.languages[l+1], err)
+			continue
+		}
+
+		for j, src := range pairs {
+			for i, c := range f.Properties.Choices {
+				if c == src {
+					translated[i][l+1] = tf.Properties.Choices[j]
+				}
+			}
+		}
+	}
+
+	for i := range f.Properties.Choices {
+		row := []string{f.Ref, choiceName(f, i)}
+		for _, c := range translated[i] {
+			label := ""
+			if c != nil {
+				label = x.text("Question "+f.Ref, c.Label)
+			}
+			row = append(row, label)
+		}
+		x.choices = append(x.choices, row)
+	}
+}
+
+func (x *xlsformExport) addYesNo() {
+	if x.lists["yes_no"] {
+		return
+	}
+	x.lists["yes_no"] = true
+
+	// yes_no questions have no labels in Typeform
+	labels := make([][2]string, len(x.languages))
+	for i, l := range x.languages {
+		yesNo, ok := yesNoLabels[l]
+		if !ok {
+			yesNo = yesNoLabels["en"]
+			if l != "" {
+				x.report("There are no yes/no labels for %s, yes_no questions have English ones", l)
+			}
+		}
+		labels[i] = yesNo
+	}
+
+	for j, name := range []string{"yes", "no"} {
+		row := []string{"yes_no", name}
+		for _, l := range labels {
+			row = append(row, l[j])
+		}
+		x.choices = append(x.choices, row)
+	}
+}
+
+func (x *xlsformExport) addRow(typ, name, required, relevant, appearance, constraint, parameters string, labels, hints []string) {
+	row := []string{typ, name, required, relevant, appearance, constraint, parameters}
+	row = append(row, labels...)
+	row = append(row, hints...)
+	x.survey = append(x.survey, row)
+}
+
+func (x *xlsformExport) addField(f *Field, relevant map[string]string) {
+	labels, hints := x.labels(f)
+
+	if f.Type == "group" || f.Type == "matrix" {
+		x.addRow("begin_group", f.Ref, "", relevant[f.Ref], "", "", "", labels, hints)
+		for _, g := range f.Properties.Fields {
+			x.addField(g, relevant)
+		}
+		x.addRow("end_group", f.Ref, "", "", "", "", "", make([]string, len(labels)), make([]string, len(hints)))
+		return
+	}
+
+	required := ""
+	constraint := []string{}
+	if v := f.Validations; v != nil {
+		if v.Required {
+			required = "yes"
+		}
+		if v.MinValue != nil {
+			constraint = append(constraint, fmt.Sprintf(". >= %d", *v.MinValue))
+		}
+		if v.MaxValue != nil {
+			constraint = append(constraint, fmt.Sprintf(". <= %d", *v.MaxValue))
+		}
+	}
+
+	appearance, parameters := "", ""
+	typ, ok := exportTypes[f.Type]
+
+	switch f.Type {
+	case "long_text":
+		appearance = "multiline"
+	case "multiple_choice", "dropdown", "picture_choice":
+		typ = "select_one " + f.Ref
+		if p := f.Properties; p.AllowMultipleSelection != nil && *p.AllowMultipleSelection {
+			typ = "select_multiple " + f.Ref
+		}
+		if p := f.Properties; p.AllowOtherChoice != nil && *p.AllowOtherChoice {
+			typ += " or_other"
+		}
+		if f.Type == "dropdown" {
+			appearance = "minimal"
+		}
+		x.addList(f)
+		ok = true
+	case "ranking":
+		typ = "rank " + f.Ref
+		x.addList(f)
+	case "yes_no":
+		typ = "select_one yes_no"
+		x.addYesNo()
+		ok = true
+	case "opinion_scale", "rating":
+		start := 1
+		if f.Type == "opinion_scale" && !f.Properties.StartAtOne {
+			start = 0
+		}
+		steps := f.Properties.Steps
+		if steps == 0 {
+			steps = map[string]int{"opinion_scale": 11, "rating": 5}[f.Type]
+		}
+		typ, ok = "range", true
+		parameters = fmt.Sprintf("start=%d end=%d step=1", start, start+steps-1)
+	}
+
+	if !ok {
+		x.report("Question %s is %s, which cannot be made in XLSForm", f.Ref, f.Type)
+		return
+	}
+
+	x.addRow(typ, f.Ref, required, relevant[f.Ref], appearance, strings.Join(constraint, " and "), parameters, labels, hints)
+}
+
+// jumpCondition is the XLSForm expression of the condition of a jump
+func jumpCondition(c *LogicCondition) (string, bool) {
+	if c.Op != "is" || len(c.Vars) != 2 {
+		return "", false
+	}
+
+	ref, value := fmt.Sprint(c.Vars[0].Value), c.Vars[1]
+	switch value.Type {
+	case "choice":
+		return fmt.Sprintf("selected(${%s}, '%v')", ref, value.Value), true
+	case "constant":
+		if b, ok := value.Value.(bool); ok {
+			name := "no"
+			if b {
+				name = "yes"
+			}
+			return fmt.Sprintf("${%s} = '%s'", ref, name), true
+		}
+	}
+	return "", false
+}
+
+// relevant turns the jumps of the form into relevant expressions: a
+// question is only relevant if no jump before it skips it. Jumps are
+// checked in order, so an always jump only skips a question if none of
+// the jumps before it is taken.
+func (x *xlsformExport) relevant() map[string]string {
+	fields := flattenFields(x.base.Fields)
+	conditions := map[string][]string{}
+
+	logic := []*FieldLogic{}
+	if len(x.base.Logic) > 0 {
+		if err := json.Unmarshal(x.base.Logic, &logic); err != nil {
+			x.report("Could not read the logic of the form: %s", err)
+			return nil
+		}
+	}
+
+	for _, l := range logic {
+		from := fieldIndex(l.Ref, fields)
+		skips := make([][]string, len(fields))
+		earlier := []string{}
+
+		for _, a := range l.Actions {
+			if a.Action != "jump" {
+				x.report("Question %s: the %s action cannot be exported", l.Ref, a.Action)
+				continue
+			}
+
+			to := len(fields)
+			if a.Details.To.Type == "field" {
+				to = fieldIndex(fmt.Sprint(a.Details.To.Value), fields)
+			}
+
+			condition, ok := "", true
+			if a.Condition.Op == "always" {
+				condition = "true()"
+				if len(earlier) > 0 {
+					condition = fmt.Sprintf("not(%s)", strings.Join(earlier, " or "))
+				}
+			} else if condition, ok = jumpCondition(a.Condition); !ok {
+				x.report("Question %s: a jump on %s cannot be exported", l.Ref, a.Condition.Op)
+				continue
+			}
+
+			for i := from + 1; i < to && from >= 0; i++ {
+				skips[i] = append(skips[i], condition)
+			}
+			earlier = append(earlier, condition)
+
+			if a.Condition.Op == "always" {
+				break
+			}
+		}
+
+		for i, s := range skips {
+			if len(s) > 0 {
+				ref := fields[i].Ref
+				conditions[ref] = append(conditions[ref], fmt.Sprintf("not(%s)", strings.Join(s, " or ")))
+			}
+		}
+	}
+
+	relevant := map[string]string{}
+	for ref, c := range conditions {
+		relevant[ref] = strings.Join(c, " and ")
+	}
+	return relevant
+}
+
+func formID(title string) string {
+	id := strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(title), "_"), "_")
+	if id == "" {
+		return "form"
+	}
+	return id
+}
+
+// ExportXLSForm writes the form as an XLSForm workbook, with the
+// translations of the form, by language code, as more label and hint
+// columns. It returns everything that could not be exported.
+func ExportXLSForm(path string, form *Form, translations map[string]*Form) ([]string, error) {
+	x := &xlsformExport{base: form, lists: map[string]bool{}}
+
+	base := ""
+	if form.Settings != nil {
+		base = form.Settings.Language
+	}
+
+	codes := []string{}
+	for code := range translations {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	forms := []*Form{form}
+	for _, code := range codes {
+		forms = append(forms, translations[code])
+	}
+
+	x.languages = append([]string{base}, codes...)
+	for _, f := range forms {
+		fields := map[string]*Field{}
+		for _, field := range flattenFields(f.Fields) {
+			fields[field.Ref] = field
+		}
+		x.forms = append(x.forms, fields)
+
+		screens := map[string]*WelcomeScreen{}
+		for _, s := range f.WelcomeScreens {
+			screens[s.Ref] = s
+		}
+		x.screens = append(x.screens, screens)
+	}
+
+	labelColumns := func(column string) []string {
+		columns := []string{}
+		for _, l := range x.languages {
+			if l == "" {
+				columns = append(columns, column)
+			} else {
+				columns = append(columns, fmt.Sprintf("%s::%s", column, languageLabel(l)))
+			}
+		}
+		return columns
+	}
+
+	surveyHeader := []string{"type", "name", "required", "relevant", "appearance", "constraint", "parameters"}
+	surveyHeader = append(surveyHeader, labelColumns("label")...)
+	surveyHeader = append(surveyHeader, labelColumns("hint")...)
+	x.survey = [][]string{surveyHeader}
+	x.choices = [][]string{append([]string{"list_name", "name"}, labelColumns("label")...)}
+
+	for _, s := range form.WelcomeScreens {
+		x.addRow("note", s.Ref, "", "", "", "", "", x.screenLabels(s), make([]string, len(x.languages)))
+	}
+
+	relevant := x.relevant()
+	for _, f := range form.Fields {
+		x.addField(f, relevant)
+	}
+
+	for _, s := range form.ThankYouScreens {
+		x.report("Thankyou screen %s cannot be exported", s.Ref)
+	}
+	for _, h := range form.Hidden {
+		x.report("Hidden field %s cannot be exported", h)
+	}
+
+	settings := [][]string{
+		{"form_title", "form_id", "default_language"},
+		{form.Title, formID(form.Title), languageLabel(base)},
+	}
+
+	ex := excelize.NewFile()
+	defer ex.Close()
+
+	sheets := []struct {
+		name string
+		rows [][]string
+	}{{"survey", x.survey}, {"choices", x.choices}, {"settings", settings}}
+
+	for i, s := range sheets {
+		if i == 0 {
+			ex.SetSheetName("Sheet1", s.name)
+		} else {
+			ex.NewSheet(s.name)
+		}
+
+		for r, row := range s.rows {
+			err := ex.SetSheetRow(s.name, fmt.Sprintf("A%d", r+1), &row)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return x.problems, ex.SaveAs(path)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+func readSheet(t *testing.T, path, sheet string) [][]string {
+	f, err := excelize.OpenFile(path)
+	assert.Nil(t, err)
+	defer f.Close()
+
+	rows, err := f.GetRows(sheet)
+	assert.Nil(t, err)
+	return rows
+}
+
+func TestExportXLSForm_WritesSurveyAndChoices(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "options", "description", "required", "validations", "choice_refs"})
+	form, _, err := BuildForm("Household Survey", cols, nil, [][]string{
+		{"consent", "multiple_choice", "Do you consent?", "Yes\nNo", "", "yes", "", "yes\nno"},
+		{"name", "short_text", "Name?", "", "Your full name"},
+		{"age", "number", "How old is {{field:name}}?", "", "", "", "min_value=0; max_value=120"},
+		{"likes", "yes_no", "Do you like it?"},
+		{"bye", "thankyou_screen", "Bye"},
+	})
+	assert.Nil(t, err)
+	form.Settings = &FormSettings{Language: "en"}
+
+	translation, _, err := BuildForm("Encuesta", cols, nil, [][]string{
+		{"consent", "multiple_choice", "¿Está de acuerdo?", "No\nSí", "", "", "", "no\nyes"},
+		{"name", "short_text", "¿Nombre?", "", "Su nombre completo"},
+		{"age", "number", "¿Qué edad tiene {{field:name}}?"},
+		{"likes", "yes_no", "¿Le gusta?"},
+	})
+	assert.Nil(t, err)
+
+	path := filepath.Join(t.TempDir(), "household.xlsx")
+	problems, err := ExportXLSForm(path, form, map[string]*Form{"es": translation})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Thankyou screen bye cannot be exported"}, problems)
+
+	survey := readSheet(t, path, "survey")
+	assert.Equal(t, []string{"type", "name", "required", "relevant", "appearance", "constraint", "parameters",
+		"label::English (en)", "label::Spanish (es)", "hint::English (en)", "hint::Spanish (es)"}, survey[0])
+	assert.Equal(t, []string{"select_one consent", "consent", "yes", "", "", "", "", "Do you consent?", "¿Está de acuerdo?"}, survey[1])
+	assert.Equal(t, []string{"text", "name", "", "", "", "", "", "Name?", "¿Nombre?", "Your full name", "Su nombre completo"}, survey[2])
+	assert.Equal(t, []string{"integer", "age", "", "", "", ". >= 0 and . <= 120", "", "How old is ${name}?", "¿Qué edad tiene ${name}?"}, survey[3])
+	assert.Equal(t, "select_one yes_no", survey[4][0])
+
+	choices := readSheet(t, path, "choices")
+	assert.Equal(t, [][]string{
+		{"list_name", "name", "label::English (en)", "label::Spanish (es)"},
+		{"consent", "yes", "Yes", "Sí"},
+		{"consent", "no", "No", "No"},
+		{"yes_no", "yes", "Yes", "Sí"},
+		{"yes_no", "no", "No", "No"},
+	}, choices)
+
+	settings := readSheet(t, path, "settings")
+	assert.Equal(t, []string{"Household Survey", "household_survey", "English (en)"}, settings[1])
+}
+
+func TestExportXLSForm_TranslatesWelcomeScreens(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question"})
+	form, _, err := BuildForm("Survey", cols, nil, [][]string{
+		{"hello", "welcome_screen", "Welcome!"},
+		{"name", "short_text", "Name?"},
+	})
+	assert.Nil(t, err)
+	form.Settings = &FormSettings{Language: "en"}
+
+	translation, _, err := BuildForm("Encuesta", cols, nil, [][]string{
+		{"hello", "welcome_screen", "¡Bienvenido!"},
+		{"name", "short_text", "¿Nombre?"},
+	})
+	assert.Nil(t, err)
+
+	path := filepath.Join(t.TempDir(), "survey.xlsx")
+	problems, err := ExportXLSForm(path, form, map[string]*Form{"es": translation, "fr": {Fields: translation.Fields}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Welcome screen hello has no fr translation"}, problems)
+
+	survey := readSheet(t, path, "survey")
+	assert.Equal(t, []string{"note", "hello", "", "", "", "", "", "Welcome!", "¡Bienvenido!"}, survey[1])
+}
+
+func TestExportXLSForm_TurnsJumpsIntoRelevant(t *testing.T) {
+	form := logicForm()
+	logic, err := BuildLogic(form, "Sheet1", [][]string{
+		{"field", "choice", "jump_to"},
+		{"consent", "No", "optout"},
+		{"consent", "always", "name"},
+		{"likes", "no", "bye"},
+	})
+	assert.Nil(t, err)
+	form.Logic = logic
+
+	path := filepath.Join(t.TempDir(), "logic.xlsx")
+	_, err = ExportXLSForm(path, form, nil)
+	assert.Nil(t, err)
+
+	relevant := map[string]string{}
+	for _, row := range readSheet(t, path, "survey")[1:] {
+		relevant[row[1]] = get(row, 3)
+	}
+
+	assert.Equal(t, map[string]string{
+		"consent": "",
+		"likes":   "not(selected(${consent}, 'consent_2') or not(selected(${consent}, 'consent_2')))",
+		"name":    "not(selected(${consent}, 'consent_2')) and not(${likes} = 'no')",
+		"bye":     "not(selected(${consent}, 'consent_2'))",
+	}, relevant)
+}
+
+func TestExportXLSForm_ImportsBack(t *testing.T) {
+	cols, _ := ParseColumns([]string{"ref", "type", "question", "options", "properties", "choice_refs"})
+	form, _, err := BuildForm("Fruits", cols, nil, [][]string{
+		{"fruits", "multiple_choice", "Which fruits?", "Apple\nPear", "allow_multiple_selection=yes", "apple\npear"},
+		{"story", "long_text", "Why?"},
+	})
+	assert.Nil(t, err)
+
+	path := filepath.Join(t.TempDir(), "fruits.xlsx")
+	problems, err := ExportXLSForm(path, form, nil)
+	assert.Nil(t, err)
+	assert.Empty(t, problems)
+
+	conf, problems, err := ImportXLSForm("work", path)
+	assert.Nil(t, err)
+	assert.Empty(t, problems)
+
+	assert.Equal(t, "Fruits", conf.Name)
+	assert.Equal(t, "apple", conf.Form.Fields[0].Properties.Choices[0].Ref)
+	assert.True(t, *conf.Form.Fields[0].Properties.AllowMultipleSelection)
+	assert.Equal(t, "long_text", conf.Form.Fields[1].Type)
+}
+
+func writeProject(t *testing.T, base, translation [][]string) string {
+	dir := t.TempDir()
+	messages := [][]string{{"variable", "message"}}
+	writeWorkbook(t, filepath.Join(dir, "eng.xlsx"), []testSheet{{"Baseline", base}, {"Messages", messages}})
+	writeWorkbook(t, filepath.Join(dir, "spa.xlsx"), []testSheet{{"Baseline", translation}, {"Messages", messages}})
+
+	path := filepath.Join(dir, "project.yaml")
+	ioutil.WriteFile(path, []byte(`
+workspace: foo
+name: Household
+baseFile:
+  lang: English
+  path: eng.xlsx
+translationFiles:
+  - lang: Spanish
+    path: spa.xlsx
+`), 0644)
+	return path
+}
+
+func TestRun_ExportsProjectWithoutUploadingIt(t *testing.T) {
+	config := writeProject(t, [][]string{
+		{"ref", "type", "question", "options", "choice_refs"},
+		{"name", "short_text", "Name?"},
+		{"fruit", "multiple_choice", "Fruit?", "Apple\nPear", "apple\npear"},
+		{"likes", "yes_no", "Do you like it?"},
+	}, [][]string{
+		{"ref", "type", "question", "options", "choice_refs"},
+		{"name", "short_text", "¿Nombre?"},
+		{"fruit", "multiple_choice", "¿Fruta?", "Pera\nManzana", "pear\napple"},
+		{"likes", "yes_no", "¿Le gusta?"},
+	})
+
+	ts, _ := testServer(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected request to Typeform: %s %s", r.Method, r.URL.Path)
+	})
+	uploader := TypeformUploader{BaseUrl: ts.URL, TypeformToken: "secret"}
+
+	out := filepath.Join(t.TempDir(), "xlsforms")
+	assert.Nil(t, run(uploader, options{config: config, xlsformOut: out}))
+
+	survey := readSheet(t, filepath.Join(out, "Baseline.xlsx"), "survey")
+	assert.Equal(t, []string{"text", "name", "", "", "", "", "", "Name?", "¿Nombre?"}, survey[1])
+
+	choices := readSheet(t, filepath.Join(out, "Baseline.xlsx"), "choices")
+	assert.Equal(t, [][]string{
+		{"list_name", "name", "label::English (en)", "label::Spanish (es)"},
+		{"fruit", "apple", "Apple", "Manzana"},
+		{"fruit", "pear", "Pear", "Pera"},
+		{"yes_no", "yes", "Yes", "Sí"},
+		{"yes_no", "no", "No", "No"},
+	}, choices)
+}
+
+func TestRun_FailsToExportTranslationsThatCannotBeBuilt(t *testing.T) {
+	config := writeProject(t, [][]string{
+		{"ref", "type", "question"},
+		{"name", "short_text", "Name?"},
+	}, [][]string{
+		{"ref", "type", "question"},
+		{"name", "short_text", "Hola {{field:nombre}}"},
+	})
+
+	ts, _ := testServer(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected request to Typeform: %s %s", r.Method, r.URL.Path)
+	})
+	uploader := TypeformUploader{BaseUrl: ts.URL, TypeformToken: "secret"}
+
+	out := filepath.Join(t.TempDir(), "xlsforms")
+	err := run(uploader, options{config: config, xlsformOut: out})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Baseline")
+
+	_, err = os.Stat(out)
+	assert.True(t, os.IsNotExist(err))
+}
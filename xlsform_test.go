@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeXLSForm(t *testing.T, survey [][]string) string {
+	path := filepath.Join(t.TempDir(), "household.xlsx")
+	writeWorkbook(t, path, []testSheet{
+		{"survey", survey},
+		{"choices", [][]string{
+			{"list_name", "name", "label::English (en)", "label::Español (es)"},
+			{"yes_no", "yes", "Yes", "Sí"},
+			{"yes_no", "no", "No", "No"},
+			{"yes_no", "dk", "Don't know", "No sé"},
+			{"fruits", "apple", "Apple", "Manzana"},
+			{"fruits", "pear", "Pear", "Pera"},
+		}},
+		{"settings", [][]string{
+			{"form_title", "form_id", "default_language"},
+			{"Household Survey", "household", "English (en)"},
+		}},
+	})
+	return path
+}
+
+func TestImportXLSForm_MapsQuestions(t *testing.T) {
+	path := writeXLSForm(t, [][]string{
+		{"type", "name", "label::English (en)", "hint::English (en)", "required", "appearance", "constraint"},
+		{"start", "start"},
+		{"note", "intro", "Welcome"},
+		{"text", "name", "What is your name?", "Your full name", "yes"},
+		{"text", "story", "Tell us about ${name}", "", "", "multiline"},
+		{"integer", "age", "Age?", "", "yes", "", ". >= 0 and . <= 120"},
+		{"select_one yes_no", "consent", "Do you agree?", "", "", "minimal"},
+		{"select_multiple fruits or_other", "fruits", "Which fruits?"},
+		{"geopoint", "location", "Where are you?"},
+	})
+
+	conf, problems, err := ImportXLSForm("work", path)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "Household Survey", conf.Name)
+	assert.Equal(t, "en", conf.Form.Settings.Language)
+
+	fields := conf.Form.Fields
+	assert.Equal(t, 6, len(fields))
+
+	assert.Equal(t, "statement", fields[0].Type)
+	assert.Equal(t, "short_text", fields[1].Type)
+	assert.Equal(t, "Your full name", fields[1].Properties.Description)
+	assert.True(t, fields[1].Validations.Required)
+	assert.Equal(t, "long_text", fields[2].Type)
+	assert.Equal(t, "Tell us about {{field:name}}", fields[2].Title)
+	assert.Equal(t, "number", fields[3].Type)
+	assert.Equal(t, 0, *fields[3].Validations.MinValue)
+	assert.Equal(t, 120, *fields[3].Validations.MaxValue)
+
+	assert.Equal(t, "dropdown", fields[4].Type)
+	assert.Equal(t, "Yes", fields[4].Properties.Choices[0].Label)
+	assert.Equal(t, "yes", fields[4].Properties.Choices[0].Ref)
+
+	assert.Equal(t, "multiple_choice", fields[5].Type)
+	assert.True(t, *fields[5].Properties.AllowMultipleSelection)
+	assert.True(t, *fields[5].Properties.AllowOtherChoice)
+
+	assert.Equal(t, []string{
+		"survey row 2: start is metadata, which Typeform does not collect",
+		"survey row 9: geopoint questions (location) cannot be made in Typeform",
+	}, problems)
+}
+
+func TestImportXLSForm_MakesJumpsFromRelevant(t *testing.T) {
+	path := writeXLSForm(t, [][]string{
+		{"type", "name", "label", "relevant"},
+		{"select_one yes_no", "consent", "Do you agree?"},
+		{"text", "name", "Name?", "${consent} = 'yes'"},
+		{"integer", "age", "Age?", "${consent} = 'yes'"},
+		{"select_one fruits", "fruit", "Fruit?"},
+		{"text", "why", "Why?", "selected(${fruit}, 'pear') or ${fruit} = 'apple'"},
+		{"text", "city", "City?", "${age} > 18"},
+		{"note", "bye", "Thanks"},
+	})
+
+	conf, problems, err := ImportXLSForm("work", path)
+	assert.Nil(t, err)
+
+	logic := []*FieldLogic{}
+	assert.Nil(t, json.Unmarshal(conf.Form.Logic, &logic))
+
+	assert.Equal(t, 1, len(logic))
+	assert.Equal(t, "consent", logic[0].Ref)
+	assert.Equal(t, 2, len(logic[0].Actions))
+	assert.Equal(t, "fruit", logic[0].Actions[0].Details.To.Value)
+	assert.Equal(t, "no", logic[0].Actions[0].Condition.Vars[1].Value)
+	assert.Equal(t, "dk", logic[0].Actions[1].Condition.Vars[1].Value)
+
+	assert.Equal(t, []string{
+		"survey row 7: the condition ${age} > 18 of city cannot be made with jumps",
+	}, problems)
+}
+
+func TestImportXLSForm_ReportsConditionsOnEarlierQuestions(t *testing.T) {
+	path := writeXLSForm(t, [][]string{
+		{"type", "name", "label", "relevant"},
+		{"select_one yes_no", "consent", "Do you agree?"},
+		{"text", "name", "Name?"},
+		{"text", "city", "City?", "${consent} = 'yes'"},
+		{"text", "last", "Anything else?"},
+	})
+
+	conf, problems, err := ImportXLSForm("work", path)
+	assert.Nil(t, err)
+	assert.Nil(t, conf.Form.Logic)
+	assert.Equal(t, []string{
+		"survey row 4: the condition ${consent} = 'yes' of city is on consent, which is not the question right before it",
+	}, problems)
+}
+
+func TestImportXLSForm_PutsQuestionsInGroups(t *testing.T) {
+	path := writeXLSForm(t, [][]string{
+		{"type", "name", "label"},
+		{"begin_group", "household", "Household"},
+		{"integer", "members", "How many people live here?"},
+		{"begin group", "children", "Children"},
+		{"integer", "kids", "How many children?"},
+		{"end group", "children"},
+		{"end_group", "household"},
+		{"text", "city", "City?"},
+	})
+
+	conf, problems, err := ImportXLSForm("work", path)
+	assert.Nil(t, err)
+
+	fields := conf.Form.Fields
+	assert.Equal(t, 2, len(fields))
+	assert.Equal(t, "group", fields[0].Type)
+	assert.Equal(t, 2, len(fields[0].Properties.Fields))
+	assert.Equal(t, "kids", fields[0].Properties.Fields[1].Ref)
+	assert.Equal(t, []string{
+		"survey row 4: group children is inside of group household, its questions are put in household",
+	}, problems)
+}
+
+func TestImportXLSForm_NeedsSurveySheet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.xlsx")
+	writeWorkbook(t, path, []testSheet{{"choices", [][]string{{"list_name", "name", "label"}}}})
+
+	_, _, err := ImportXLSForm("work", path)
+	assert.NotNil(t, err)
+}